@@ -3,51 +3,317 @@ package main
 import (
 	"fmt"
 	"os"
+	"strings"
 
+	"github.com/blang/semver/v4"
 	"github.com/jedib0t/go-pretty/v6/table"
 	"github.com/jedib0t/go-pretty/v6/text"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"k8s.io/klog"
 
+	"github.com/flavio/kuberlr/internal/common"
+	"github.com/flavio/kuberlr/internal/config"
 	"github.com/flavio/kuberlr/internal/finder"
+	"github.com/flavio/kuberlr/internal/kubehelper"
 )
 
-func printBinTable(bins finder.KubectlBinaries) {
+func printBinTable(bins finder.KubectlBinaries, scores map[string]uint64) {
 	tableWriter := table.NewWriter()
 	tableWriter.SetOutputMirror(os.Stdout)
-	tableWriter.AppendHeader(table.Row{"#", "Version", "Binary"})
+	if scores == nil {
+		tableWriter.AppendHeader(table.Row{"#", "Version", "Binary"})
+	} else {
+		tableWriter.AppendHeader(table.Row{"#", "Version", "Binary", "Score"})
+	}
 	for i, b := range bins {
-		tableWriter.AppendRow([]interface{}{i + 1, b.Version, b.Path})
+		row := table.Row{i + 1, b.Version, b.Path}
+		if scores != nil {
+			if score, compatible := scores[b.Path]; compatible {
+				row = append(row, score)
+			} else {
+				row = append(row, "-")
+			}
+		}
+		tableWriter.AppendRow(row)
 	}
 	tableWriter.Render()
 }
 
+// binInfo is the machine-readable description of a kubectl binary.
+type binInfo struct {
+	Version string `json:"version"`
+	Path    string `json:"path"`
+	// Origin is either local or system
+	Origin string `json:"origin"`
+	Size   int64  `json:"size"`
+	// Compatible tells whether the binary can be used with the current
+	// context
+	Compatible bool    `json:"compatible"`
+	Score      *uint64 `json:"score,omitempty"`
+}
+
+// binsInfo is the machine-readable output of `kuberlr bins`.
+type binsInfo struct {
+	Context          string    `json:"context,omitempty"`
+	RequestedVersion string    `json:"requestedVersion,omitempty"`
+	RequestedRange   string    `json:"requestedRange,omitempty"`
+	Binaries         []binInfo `json:"binaries"`
+	Errors           []string  `json:"errors,omitempty"`
+}
+
 // NewBinsCmd creates a new `kuberlr bins` cobra command.
 func NewBinsCmd() *cobra.Command {
+	var output string
+
 	//nolint: forbidigo // it's fine to print to stdout
-	return &cobra.Command{
-		Use:   "bins",
-		Short: "Print information about the kubectl binaries found",
-		Run: func(_ *cobra.Command, _ []string) {
-			kubectlFinder := finder.NewKubectlFinder("", "")
+	cmd := &cobra.Command{
+		Use:          "bins",
+		Short:        "Print information about the kubectl binaries found",
+		SilenceUsage: true,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			if err := validateOutput(output); err != nil {
+				return err
+			}
+
+			cfg := config.NewCfg()
+			v, err := cfg.Load()
+			if err != nil {
+				return fmt.Errorf("load config: %w", err)
+			}
+
+			if output != "" {
+				info, infoErr := collectBinsInfo(v)
+				if infoErr != nil {
+					return infoErr
+				}
+				return printStructured(output, info)
+			}
+
+			settings, err := loadKubectlSettings(v, nil)
+			if err != nil {
+				return fmt.Errorf("load kubectl settings: %w", err)
+			}
+			printVersionFile(settings)
+			printContextRule(settings)
+
+			kubectlFinder := finder.NewKubectlFinder("", config.SystemPaths(v))
+			versioner := settings.newVersioner(kubectlFinder)
+			compatibility, err := binsCompatibilityOf(settings, versioner)
+			if err != nil {
+				klog.V(common.VerbosityOne).Infof("cannot find the compatible kubectl binaries: %v", err)
+			}
+			printCandidates(settings, versioner, compatibility)
+			scores := compatibility.Scores
+
 			systemBins, err := kubectlFinder.SystemKubectlBinaries()
 
-			fmt.Printf("%s\n", text.FgGreen.Sprint("system-wide kubectl binaries"))
-			printBinaries(systemBins, err)
+			fmt.Printf("%s %s\n",
+				text.FgGreen.Sprint("system-wide kubectl binaries"),
+				strings.Join(kubectlFinder.SystemPaths(), string(os.PathListSeparator)))
+			printBinaries(systemBins, scores, err)
 
 			fmt.Printf("\n\n")
 			localBins, err := kubectlFinder.LocalKubectlBinaries()
 
 			fmt.Printf("%s\n", text.FgGreen.Sprint("local kubectl binaries"))
-			printBinaries(localBins, err)
+			printBinaries(localBins, scores, err)
+
+			return nil
 		},
 	}
+
+	cmd.Flags().StringVarP(&output, "output", "o", "", "output format, valid values are: json, yaml")
+
+	return cmd
 }
 
-func printBinaries(bins finder.KubectlBinaries, err error) {
+// binsCompatibility tells which kubectl binaries can be used with the
+// current context.
+type binsCompatibility struct {
+	// RequestedVersion is the version kubectl must be compatible with, or
+	// be exactly when it's pinned. It's nil when unknown.
+	RequestedVersion *semver.Version
+	// RequestedRange is set when the version file pins a range of versions
+	RequestedRange string
+	// Scores holds the ranking score of the compatible kubectl binaries,
+	// indexed by path. It's nil when the binaries are not ranked.
+	Scores map[string]uint64
+
+	accepts func(b finder.KubectlBinary) bool
+}
+
+// Compatible returns true when the given binary can be used with the
+// current context.
+func (c binsCompatibility) Compatible(b finder.KubectlBinary) bool {
+	return c.accepts != nil && c.accepts(b)
+}
+
+// binsCompatibilityOf finds the kubectl binaries that can be used with the
+// current context. The API server is never contacted: unless the version
+// is pinned, the last known version of the API server is used. Nothing is
+// compatible when that version is unknown.
+func binsCompatibilityOf(settings kubectlSettings, versioner *finder.Versioner) (binsCompatibility, error) {
+	if settings.VersionFile != nil && settings.VersionFile.Range != nil {
+		return binsCompatibility{
+			RequestedRange: settings.VersionFile.Constraint,
+			accepts: func(b finder.KubectlBinary) bool {
+				return settings.VersionFile.Range.Contains(b.Version)
+			},
+		}, nil
+	}
+
+	if settings.pinnedExactly() {
+		// the version is pinned, the API server is not involved
+		requested, err := settings.kubectlVersionToUse(versioner)
+		if err != nil {
+			return binsCompatibility{}, err
+		}
+		return binsCompatibility{
+			RequestedVersion: &requested.Version,
+			accepts: func(b finder.KubectlBinary) bool {
+				return b.Version.Equals(requested.Version)
+			},
+		}, nil
+	}
+
+	version, found, err := contextServerVersion(settings)
+	if err != nil || !found {
+		return binsCompatibility{}, err
+	}
+
+	scores := map[string]uint64{}
+	for _, r := range versioner.RankCompatibleKubectl(version) {
+		scores[r.Path] = r.Score
+	}
+	return binsCompatibility{
+		RequestedVersion: &version,
+		Scores:           scores,
+		accepts: func(b finder.KubectlBinary) bool {
+			_, compatible := scores[b.Path]
+			return compatible
+		},
+	}, nil
+}
+
+// collectBinsInfo describes all the kubectl binaries found, telling which
+// ones are compatible with the current context.
+func collectBinsInfo(v *viper.Viper) (binsInfo, error) {
+	settings, err := loadKubectlSettings(v, nil)
+	if err != nil {
+		return binsInfo{}, fmt.Errorf("load kubectl settings: %w", err)
+	}
+	kubectlFinder := finder.NewKubectlFinder("", config.SystemPaths(v))
+
+	info := binsInfo{
+		Context:  currentContextName(settings),
+		Binaries: []binInfo{},
+	}
+	compatibility, err := binsCompatibilityOf(settings, settings.newVersioner(kubectlFinder))
+	if err != nil {
+		info.Errors = append(info.Errors, err.Error())
+	}
+	if compatibility.RequestedVersion != nil {
+		info.RequestedVersion = compatibility.RequestedVersion.String()
+	}
+	info.RequestedRange = compatibility.RequestedRange
+
+	localBins, err := kubectlFinder.LocalKubectlBinaries()
+	if err != nil {
+		info.Errors = append(info.Errors, err.Error())
+	}
+	systemBins, err := kubectlFinder.SystemKubectlBinaries()
+	if err != nil {
+		info.Errors = append(info.Errors, err.Error())
+	}
+
+	for _, b := range append(localBins, systemBins...) {
+		bin := binInfo{
+			Version:    b.Version.String(),
+			Path:       b.Path,
+			Origin:     b.Origin,
+			Compatible: compatibility.Compatible(b),
+		}
+		if score, ranked := compatibility.Scores[b.Path]; ranked {
+			bin.Score = &score
+		}
+		if fileInfo, statErr := os.Stat(b.Path); statErr == nil {
+			bin.Size = fileInfo.Size()
+		}
+		info.Binaries = append(info.Binaries, bin)
+	}
+
+	return info, nil
+}
+
+// printCandidates prints the version the ranked kubectl binaries are
+// compatible with, or why they cannot be ranked.
+func printCandidates(settings kubectlSettings, versioner *finder.Versioner, compatibility binsCompatibility) {
+	//nolint: forbidigo // it's fine to print to stdout
+	switch {
+	case compatibility.Scores != nil:
+		fmt.Printf("%s\nversion %s, skew policy %s, ranking %v\n\n",
+			text.FgGreen.Sprint("kubectl candidates"),
+			compatibility.RequestedVersion,
+			versioner.SkewPolicy(),
+			versioner.RankingPreferences())
+	case compatibility.RequestedVersion == nil && compatibility.RequestedRange == "":
+		kubeContext := "the current context"
+		if name := currentContextName(settings); name != "" {
+			kubeContext = fmt.Sprintf("context %q", name)
+		}
+		fmt.Printf("%s\nthe version of the API server of %s is unknown, `kuberlr which` finds it\n\n",
+			text.FgGreen.Sprint("kubectl candidates"),
+			kubeContext)
+	}
+}
+
+// currentContextName returns the name of the kubeconfig context in use, an
+// empty string when it cannot be found. Only the kubeconfig files are read.
+func currentContextName(settings kubectlSettings) string {
+	if settings.Context.Name != "" {
+		return settings.Context.Name
+	}
+
+	contextInfo, err := kubehelper.CurrentContext(settings.KubectlArgs)
+	if err != nil {
+		return ""
+	}
+	return contextInfo.Name
+}
+
+func printVersionFile(settings kubectlSettings) {
+	if settings.VersionFile == nil {
+		return
+	}
+
+	//nolint: forbidigo // it's fine to print to stdout
+	fmt.Printf("%s\n%q pinned by %s\n\n",
+		text.FgGreen.Sprint("version file"),
+		settings.VersionFile.Constraint,
+		settings.VersionFile.Path)
+}
+
+func printContextRule(settings kubectlSettings) {
+	if settings.Rule == nil {
+		return
+	}
+
+	//nolint: forbidigo // it's fine to print to stdout
+	fmt.Printf("%s\n%s matches context %q (server %s)\n\n",
+		text.FgGreen.Sprint("context rule"),
+		settings.Rule,
+		settings.Context.Name,
+		settings.Context.Server)
+}
+
+func printBinaries(bins finder.KubectlBinaries, scores map[string]uint64, err error) {
 	if err != nil {
 		//nolint: forbidigo // it's fine to print to stdout
 		fmt.Printf("Error retrieving binaries: %v\n", err)
-		return
+		if len(bins) == 0 {
+			return
+		}
 	}
 
 	if len(bins) == 0 {
@@ -56,5 +322,5 @@ func printBinaries(bins finder.KubectlBinaries, err error) {
 		return
 	}
 
-	printBinTable(bins)
+	printBinTable(bins, scores)
 }
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/blang/semver/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/flavio/kuberlr/internal/common"
+	"github.com/flavio/kuberlr/internal/finder"
+)
+
+func TestBinsCompatibilityOf(t *testing.T) {
+	t.Setenv(common.HomeDirEnvKey(), t.TempDir())
+	t.Setenv("KUBECONFIG", filepath.Join(t.TempDir(), "missing"))
+
+	localDir := t.TempDir()
+	for _, name := range []string{"kubectl1.28.4", "kubectl1.29.1"} {
+		require.NoError(t, os.WriteFile(filepath.Join(localDir, name), []byte("#!/bin/sh\n"), 0o700))
+	}
+	kubectlFinder := finder.NewKubectlFinder(localDir, []string{t.TempDir()})
+	bins, err := kubectlFinder.LocalKubectlBinaries()
+	require.NoError(t, err)
+	require.Len(t, bins, 2)
+
+	policy, err := finder.NewSkewPolicy("upstream", 1, 1)
+	require.NoError(t, err)
+	versionRange, err := common.ParseVersionRange("1.29")
+	require.NoError(t, err)
+	pinned := semver.MustParse("1.28.4")
+
+	tests := []struct {
+		name               string
+		settings           kubectlSettings
+		expectedVersion    string
+		expectedCompatible []bool
+	}{
+		{
+			name:               "range pinned by the version file",
+			settings:           kubectlSettings{VersionFile: &finder.VersionFile{Constraint: "1.29", Range: versionRange}},
+			expectedCompatible: []bool{false, true},
+		},
+		{
+			name:               "version pinned by the version file",
+			settings:           kubectlSettings{VersionFile: &finder.VersionFile{Version: &pinned}},
+			expectedVersion:    "1.28.4",
+			expectedCompatible: []bool{true, false},
+		},
+		{
+			name:               "version pinned by the context rule",
+			settings:           kubectlSettings{KubectlVersion: "1.29.1"},
+			expectedVersion:    "1.29.1",
+			expectedCompatible: []bool{false, true},
+		},
+		{
+			// the API server is not contacted
+			name:               "unknown API server version",
+			settings:           kubectlSettings{KubectlArgs: []string{"--server", "https://127.0.0.1:1"}},
+			expectedCompatible: []bool{false, false},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.settings.SkewPolicy = policy
+			compatibility, err := binsCompatibilityOf(tt.settings, tt.settings.newVersioner(kubectlFinder))
+			require.NoError(t, err)
+
+			if tt.expectedVersion == "" {
+				assert.Nil(t, compatibility.RequestedVersion)
+			} else {
+				require.NotNil(t, compatibility.RequestedVersion)
+				assert.Equal(t, tt.expectedVersion, compatibility.RequestedVersion.String())
+			}
+			assert.Nil(t, compatibility.Scores)
+			for i, b := range bins {
+				assert.Equal(t, tt.expectedCompatible[i], compatibility.Compatible(b), b.Path)
+			}
+		})
+	}
+}
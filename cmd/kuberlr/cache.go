@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/jedib0t/go-pretty/v6/text"
+	"github.com/spf13/cobra"
+
+	"github.com/flavio/kuberlr/internal/config"
+	"github.com/flavio/kuberlr/internal/kubehelper"
+)
+
+// shortFingerprintLen is how many characters of the CA fingerprint are shown.
+const shortFingerprintLen = 12
+
+// NewCacheCmd creates a new `kuberlr cache` cobra command.
+func NewCacheCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "cache",
+		Short: "Inspect and reset the cache of the API server versions",
+	}
+
+	cmd.AddCommand(
+		newCacheShowCmd(),
+		newCacheClearCmd(),
+	)
+
+	return cmd
+}
+
+func loadServerVersionCache() (*kubehelper.ServerVersionCache, error) {
+	v, err := config.NewCfg().Load()
+	if err != nil {
+		return nil, fmt.Errorf("load config: %w", err)
+	}
+
+	ttl, err := config.ServerVersionCacheTTL(v)
+	if err != nil {
+		return nil, err
+	}
+
+	cache, err := kubehelper.LoadServerVersionCache(kubehelper.DefaultServerVersionCachePath(), ttl)
+	if err != nil {
+		return nil, fmt.Errorf("load server version cache: %w", err)
+	}
+
+	return cache, nil
+}
+
+func newCacheShowCmd() *cobra.Command {
+	//nolint: forbidigo // it's fine to print to stdout
+	return &cobra.Command{
+		Use:          "show",
+		Short:        "Print the cached versions of the API servers",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			cache, err := loadServerVersionCache()
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("%s %s (ttl %s)\n",
+				text.FgGreen.Sprint("server version cache"),
+				kubehelper.DefaultServerVersionCachePath(),
+				cache.TTL())
+
+			entries := cache.Entries()
+			if len(entries) == 0 {
+				fmt.Println("No API server version cached")
+				return nil
+			}
+
+			tableWriter := table.NewWriter()
+			tableWriter.SetOutputMirror(os.Stdout)
+			tableWriter.AppendHeader(table.Row{"#", "Server", "CA fingerprint", "Version", "Checked at", "Status"})
+			for i, entry := range entries {
+				status := "fresh"
+				if entry.Expired(cache.TTL()) {
+					status = "expired"
+				}
+				tableWriter.AppendRow(table.Row{
+					i + 1,
+					entry.Server,
+					shortFingerprint(entry.CAFingerprint),
+					entry.Version,
+					entry.CheckedAt.Local().Format(time.RFC3339),
+					status,
+				})
+			}
+			tableWriter.Render()
+
+			return nil
+		},
+	}
+}
+
+func newCacheClearCmd() *cobra.Command {
+	//nolint: forbidigo // it's fine to print to stdout
+	return &cobra.Command{
+		Use:          "clear",
+		Short:        "Remove all the cached versions of the API servers",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			// an unreadable cache can be cleared too
+			cache, _ := kubehelper.LoadServerVersionCache(kubehelper.DefaultServerVersionCachePath(), 0)
+			if err := cache.Clear(); err != nil {
+				return fmt.Errorf("clear server version cache: %w", err)
+			}
+
+			fmt.Println("Server version cache cleared")
+			return nil
+		},
+	}
+}
+
+func shortFingerprint(fingerprint string) string {
+	if fingerprint == "" {
+		return "-"
+	}
+	if len(fingerprint) > shortFingerprintLen {
+		return fingerprint[:shortFingerprintLen]
+	}
+	return fingerprint
+}
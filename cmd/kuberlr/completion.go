@@ -0,0 +1,256 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/blang/semver/v4"
+	"github.com/spf13/cobra"
+
+	"github.com/flavio/kuberlr/internal/config"
+	"github.com/flavio/kuberlr/internal/downloader"
+	"github.com/flavio/kuberlr/internal/finder"
+	"github.com/flavio/kuberlr/internal/kubehelper"
+)
+
+const (
+	// completedMinorVersions is the number of minor versions completed,
+	// walking the older ones would contact the mirror too many times
+	completedMinorVersions = 6
+	// completionTimeout limits the requests made to the mirror while
+	// completing, the shell is blocked until they are over
+	completionTimeout = 2 * time.Second
+)
+
+// patchPrefixRe matches a version being completed once its minor version
+// has been typed, like `1.28.`
+var patchPrefixRe = regexp.MustCompile(`^(\d+)\.(\d+)\.`) //nolint: gochecknoglobals // regexps cannot be go constants
+
+// NewCompletionCmd creates a new `kuberlr completion` cobra command.
+func NewCompletionCmd() *cobra.Command {
+	var noDescriptions bool
+
+	cmd := &cobra.Command{
+		Use:   "completion <bash|zsh|fish|powershell>",
+		Short: "Print the completion script of kuberlr for the given shell",
+		Long: `Print the completion script of kuberlr for the given shell.
+
+The versions available on the mirror are cached for an hour inside of
+` + downloader.DefaultReleasesCachePath() + `, so that completing them doesn't
+contact the mirror every time. Only the most recent minor versions are
+completed, the releases of an older one once it's typed, like 1.20.
+
+The completion of kubectl is not included, use 'kuberlr init' to enable both.`,
+		Example: `
+  Load the completion inside of the current bash session:
+  $ source <(kuberlr completion bash)
+
+  Load the completion of every fish session:
+  $ kuberlr completion fish > ~/.config/fish/completions/kuberlr.fish`,
+		Args:                  cobra.ExactArgs(1),
+		ValidArgs:             []string{"bash", "zsh", "fish", "powershell"},
+		DisableFlagsInUseLine: true,
+		SilenceUsage:          true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			root := cmd.Root()
+			switch args[0] {
+			case "bash":
+				return root.GenBashCompletionV2(os.Stdout, !noDescriptions)
+			case "zsh":
+				if noDescriptions {
+					return root.GenZshCompletionNoDesc(os.Stdout)
+				}
+				return root.GenZshCompletion(os.Stdout)
+			case "fish":
+				return root.GenFishCompletion(os.Stdout, !noDescriptions)
+			case "powershell":
+				if noDescriptions {
+					return root.GenPowerShellCompletion(os.Stdout)
+				}
+				return root.GenPowerShellCompletionWithDesc(os.Stdout)
+			}
+			return fmt.Errorf("unsupported shell %q, must be one of: bash, zsh, fish, powershell", args[0])
+		},
+	}
+
+	cmd.Flags().BoolVar(&noDescriptions, "no-descriptions", false, "disable the descriptions of the completions")
+
+	return cmd
+}
+
+// completeRemoteVersions completes the versions accepted by `kuberlr get`
+// using the releases available on the mirror: the latest release of the
+// most recent minor versions and, once a minor version has been typed, all
+// its patch releases.
+func completeRemoteVersions(_ *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	prefix := ""
+	if strings.HasPrefix(toComplete, "v") {
+		prefix = "v"
+	}
+
+	completions := []string{}
+	if match := patchPrefixRe.FindStringSubmatch(strings.TrimPrefix(toComplete, "v")); match != nil {
+		major, _ := strconv.ParseUint(match[1], 10, 64)
+		minor, _ := strconv.ParseUint(match[2], 10, 64)
+		releases := cachedReleases(func(c *downloader.ReleasesCache, d *downloader.Downloder) ([]semver.Version, error) {
+			return c.MinorReleases(d, major, minor)
+		})
+		for _, r := range releases {
+			completions = append(completions, prefix+r.String())
+		}
+		return completions, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	if prefix == "" {
+		completions = append(completions, "stable\tlatest stable release", "latest\tlatest release, including pre-releases")
+	}
+	releases := cachedReleases(func(c *downloader.ReleasesCache, d *downloader.Downloder) ([]semver.Version, error) {
+		return c.LatestReleases(d, completedMinorVersions)
+	})
+	for _, r := range releases {
+		completions = append(completions,
+			fmt.Sprintf("%s%d.%d\tlatest %d.%d release", prefix, r.Major, r.Minor, r.Major, r.Minor),
+			prefix+r.String())
+	}
+
+	return completions, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeMinorVersions completes the most recent minor versions available
+// on the mirror, like 1.28.
+func completeMinorVersions(_ *cobra.Command, _ []string, _ string) ([]string, cobra.ShellCompDirective) {
+	completions := []string{}
+	releases := cachedReleases(func(c *downloader.ReleasesCache, d *downloader.Downloder) ([]semver.Version, error) {
+		return c.LatestReleases(d, completedMinorVersions)
+	})
+	for _, r := range releases {
+		completions = append(completions, fmt.Sprintf("%d.%d", r.Major, r.Minor))
+	}
+
+	return completions, cobra.ShellCompDirectiveNoFileComp
+}
+
+// cachedReleases returns the releases available on the mirror set by the
+// configuration, going through the cache of the releases. The requests made
+// to the mirror are limited by completionTimeout. Errors are only reported
+// to the debug log of the completion.
+func cachedReleases(
+	list func(c *downloader.ReleasesCache, d *downloader.Downloder) ([]semver.Version, error),
+) []semver.Version {
+	v, err := config.NewCfg().Load()
+	if err != nil {
+		cobra.CompDebugln(fmt.Sprintf("load config: %v", err), true)
+		return nil
+	}
+	d := downloader.Downloder{
+		KubeMirrorURL: v.GetString("KubeMirrorUrl"),
+		Timeout:       time.Duration(v.GetInt64("Timeout")) * time.Second,
+	}
+	if d.Timeout <= 0 || d.Timeout > completionTimeout {
+		d.Timeout = completionTimeout
+	}
+
+	cache, err := downloader.LoadReleasesCache(downloader.DefaultReleasesCachePath(), downloader.DefaultReleasesCacheTTL)
+	if err != nil {
+		// the cache is empty, but still usable
+		cobra.CompDebugln(fmt.Sprintf("load releases cache: %v", err), true)
+	}
+
+	releases, err := list(cache, &d)
+	if err != nil {
+		cobra.CompDebugln(fmt.Sprintf("find releases on %s: %v", d.KubeMirrorURL, err), true)
+	}
+
+	return releases
+}
+
+// completeLocalVersions completes the versions of the kubectl binaries
+// downloaded by kuberlr, skipping the ones already typed.
+func completeLocalVersions(_ *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+	bins, err := finder.NewKubectlFinder("", nil).LocalKubectlBinaries()
+	if err != nil {
+		cobra.CompDebugln(fmt.Sprintf("find local kubectl binaries: %v", err), true)
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	completions := []string{}
+	seen := map[string]bool{}
+	for _, b := range bins {
+		version := b.Version.String()
+		if seen[version] || slices.Contains(args, version) || slices.Contains(args, "v"+version) {
+			continue
+		}
+		seen[version] = true
+		completions = append(completions, version+"\t"+b.Path)
+	}
+
+	return completions, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeContexts completes the names of the kubeconfig contexts, skipping
+// the ones already typed.
+func completeContexts(_ *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+	return contextNames(nil, args), cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeWhichArgs completes the values of the --context and --output
+// flags of `kuberlr which`. Flag parsing is disabled for the command, the
+// flags are found by looking at the previous argument.
+func completeWhichArgs(_ *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if value, found := strings.CutPrefix(toComplete, "--context="); found {
+		completions := []string{}
+		for _, name := range contextNames(args, nil) {
+			if strings.HasPrefix(name, value) {
+				completions = append(completions, "--context="+name)
+			}
+		}
+		return completions, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	if len(args) == 0 {
+		return nil, cobra.ShellCompDirectiveDefault
+	}
+	switch args[len(args)-1] {
+	case "--context":
+		return contextNames(args, nil), cobra.ShellCompDirectiveNoFileComp
+	case "-o", "--output":
+		if len(args) == 1 {
+			return []string{outputJSON, outputYAML}, cobra.ShellCompDirectiveNoFileComp
+		}
+	}
+
+	return nil, cobra.ShellCompDirectiveDefault
+}
+
+// completeConfigSet completes the names of the settings changed by
+// `kuberlr config set`.
+func completeConfigSet(_ *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	return config.SettingNames(), cobra.ShellCompDirectiveNoFileComp
+}
+
+// contextNames returns the names of the kubeconfig contexts, found using
+// the given kubectl arguments, minus the excluded ones.
+func contextNames(kubectlArgs, exclude []string) []string {
+	names, err := kubehelper.ContextNames(kubectlArgs)
+	if err != nil {
+		cobra.CompDebugln(fmt.Sprintf("read kubeconfig contexts: %v", err), true)
+		return nil
+	}
+
+	return slices.DeleteFunc(names, func(name string) bool {
+		return slices.Contains(exclude, name)
+	})
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/jedib0t/go-pretty/v6/text"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/flavio/kuberlr/internal/config"
+	"github.com/flavio/kuberlr/internal/finder"
+)
+
+// NewConfigCmd creates a new `kuberlr config` cobra command.
+func NewConfigCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "View, validate and change the kuberlr configuration",
+		Long: `View, validate and change the kuberlr configuration.
+
+The configuration is merged from these files, the last ones take precedence:
+  ` + strings.Join(nonEmpty(config.NewCfg().Paths), "\n  ") + `
+
+Each setting can also be overridden by a ` + config.EnvPrefix + `_<SETTING> environment
+variable, like ` + config.EnvName("Timeout") + `.`,
+	}
+
+	cmd.AddCommand(
+		newConfigViewCmd(),
+		newConfigValidateCmd(),
+		newConfigSetCmd(),
+	)
+
+	return cmd
+}
+
+func newConfigViewCmd() *cobra.Command {
+	var showOrigin bool
+
+	//nolint: forbidigo // it's fine to print to stdout
+	cmd := &cobra.Command{
+		Use:          "view",
+		Short:        "Print the effective value of all the settings",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			origins, err := config.NewCfg().Origins()
+			if err != nil {
+				return fmt.Errorf("load config: %w", err)
+			}
+
+			for _, o := range origins {
+				if showOrigin {
+					fmt.Printf("%s\t", o.Source)
+				}
+				fmt.Printf("%s = %s\n", o.Key, config.FormatValue(o.Value))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&showOrigin, "show-origin", false,
+		"show where each value comes from: a file, an environment variable or the default")
+
+	return cmd
+}
+
+func newConfigValidateCmd() *cobra.Command {
+	//nolint: forbidigo // it's fine to print to stdout
+	return &cobra.Command{
+		Use:          "validate",
+		Short:        "Look for unknown settings and invalid values inside of the configuration files",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			cfg := config.NewCfg()
+
+			failures := 0
+			for _, path := range nonEmpty(cfg.Paths) {
+				if _, err := os.Stat(path); os.IsNotExist(err) {
+					continue
+				}
+				if err := config.ValidateFile(path); err != nil {
+					failures++
+					fmt.Printf("[%s] %s\n", text.FgRed.Sprint(doctorFail), path)
+					for _, line := range strings.Split(err.Error(), "\n") {
+						fmt.Printf("       %s\n", line)
+					}
+					continue
+				}
+				fmt.Printf("[%s] %s\n", text.FgGreen.Sprint(doctorPass), path)
+			}
+			if failures > 0 {
+				return fmt.Errorf("%d configuration files are not valid", failures)
+			}
+
+			// the values can be wrong also once the files are merged with
+			// the environment variables
+			return checkConfig(cfg)
+		},
+	}
+}
+
+func newConfigSetCmd() *cobra.Command {
+	var file string
+
+	cmd := &cobra.Command{
+		Use:   "set <setting> <value>",
+		Short: "Change a setting inside of a configuration file",
+		Long: `Change a setting inside of a configuration file.
+
+The rest of the file, comments included, is left untouched. The change is
+saved only when the resulting configuration is valid.
+
+Arrays, like Ranking, are written as comma separated values:
+
+  kuberlr config set Ranking local,exact-minor
+
+The per-context settings must be changed by editing the file.`,
+		Example:           "  kuberlr config set AllowDownload false",
+		Args:              cobra.ExactArgs(2), //nolint: mnd // the setting and its value
+		ValidArgsFunction: completeConfigSet,
+		SilenceUsage:      true,
+		RunE: func(_ *cobra.Command, args []string) error {
+			cfg := config.NewCfg()
+
+			err := config.SetValue(file, args[0], args[1], func(candidate string) error {
+				if err := config.ValidateFile(candidate); err != nil {
+					return err
+				}
+				return checkConfig(&config.Cfg{Paths: replacePath(cfg.Paths, file, candidate)})
+			})
+			if err != nil {
+				return err
+			}
+
+			warnOverridden(cfg, file, args[0])
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&file, "file", config.UserConfigFile(), "configuration file to change")
+
+	return cmd
+}
+
+// checkConfig ensures the merged configuration holds valid values.
+func checkConfig(cfg *config.Cfg) error {
+	v, err := cfg.Load()
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+	if _, err = loadKubectlSettings(v, nil); err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	return checkContextRules(v)
+}
+
+// checkContextRules ensures the skew policies of all the context rules are
+// valid, not only the one of the rule matching the current context.
+func checkContextRules(v *viper.Viper) error {
+	rules, err := config.LoadContextRules(v)
+	if err != nil {
+		return err
+	}
+
+	errs := []error{}
+	for _, rule := range rules {
+		if rule.SkewPolicy == "" {
+			continue
+		}
+		var minorsBelow, minorsAbove uint64
+		if rule.SkewMinorsBelow != nil {
+			minorsBelow = *rule.SkewMinorsBelow
+		}
+		if rule.SkewMinorsAbove != nil {
+			minorsAbove = *rule.SkewMinorsAbove
+		}
+		if _, policyErr := finder.NewSkewPolicy(rule.SkewPolicy, minorsBelow, minorsAbove); policyErr != nil {
+			errs = append(errs, fmt.Errorf("invalid skew policy of rule %s: %w", rule, policyErr))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// warnOverridden tells the user when the setting just changed is
+// overridden by another configuration file or by an environment variable.
+func warnOverridden(cfg *config.Cfg, file, key string) {
+	if !slices.Contains(cfg.Paths, file) {
+		fmt.Fprintf(os.Stderr, "%s %s is not read by kuberlr unless %s points to it\n",
+			text.FgYellow.Sprint("warning:"), file, config.EnvPrefix+"_CFG")
+		return
+	}
+
+	origins, err := cfg.Origins()
+	if err != nil {
+		return
+	}
+
+	for _, o := range origins {
+		if !strings.EqualFold(o.Key, key) {
+			continue
+		}
+		if o.Source != "file:"+file {
+			fmt.Fprintf(os.Stderr, "%s %s is overridden by %s\n",
+				text.FgYellow.Sprint("warning:"), o.Key, strings.TrimPrefix(o.Source, "file:"))
+		}
+		return
+	}
+}
+
+// replacePath returns the list of paths with the given path replaced by
+// another one, which is appended when the path is not found.
+func replacePath(paths []string, path, replacement string) []string {
+	replaced := []string{}
+	found := false
+	for _, p := range paths {
+		if p == path {
+			p, found = replacement, true
+		}
+		replaced = append(replaced, p)
+	}
+	if !found {
+		replaced = append(replaced, replacement)
+	}
+
+	return replaced
+}
+
+func nonEmpty(items []string) []string {
+	result := []string{}
+	for _, item := range items {
+		if item != "" {
+			result = append(result, item)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,344 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/blang/semver/v4"
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/jedib0t/go-pretty/v6/text"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/flavio/kuberlr/internal/config"
+	"github.com/flavio/kuberlr/internal/finder"
+	"github.com/flavio/kuberlr/internal/kubehelper"
+)
+
+const (
+	defaultContextWorkers = 4
+
+	downloadNotNeeded = "no"
+	downloadNeeded    = "yes"
+	downloadDisabled  = "needed, but disabled"
+)
+
+// contextVersion is the version of the API server of a kubeconfig context.
+type contextVersion struct {
+	Context  string
+	Server   string
+	settings kubectlSettings
+	// Version is nil when it cannot be found
+	Version *semver.Version
+	// Pinned is true when the version is set by the context rule
+	Pinned bool
+	Err    error
+}
+
+// contextOverview is the kubectl that would be used with a kubeconfig
+// context.
+type contextOverview struct {
+	Context       string `json:"context"`
+	Current       bool   `json:"current"`
+	Server        string `json:"server,omitempty"`
+	ServerVersion string `json:"serverVersion,omitempty"`
+	// ServerVersionPinned is true when the version is set by the context
+	// rule instead of being reported by the API server
+	ServerVersionPinned bool `json:"serverVersionPinned,omitempty"`
+	// ServerStatus is empty when the version of the API server is known,
+	// otherwise it tells why it's not
+	ServerStatus   string `json:"serverStatus,omitempty"`
+	ServerError    string `json:"serverError,omitempty"`
+	KubectlVersion string `json:"kubectlVersion,omitempty"`
+	KubectlPath    string `json:"kubectlPath,omitempty"`
+	// Fallback is true when the kubectl is picked without knowing the
+	// version of the API server
+	Fallback bool   `json:"fallback,omitempty"`
+	Download string `json:"download"`
+	Error    string `json:"error,omitempty"`
+}
+
+// NewContextsCmd creates a new `kuberlr contexts` cobra command.
+func NewContextsCmd() *cobra.Command {
+	var output string
+	workers := defaultContextWorkers
+
+	cmd := &cobra.Command{
+		Use:   "contexts [pattern...]",
+		Short: "Show the kubectl used with each kubeconfig context",
+		Long: `Show the kubectl used with each kubeconfig context.
+
+The API servers of all the contexts are contacted to find their version, then
+the compatible kubectl binary that would be used is shown. Nothing is
+downloaded.
+
+The contexts can be filtered by name using exact names, globs (prod-*) or
+regular expressions written between slashes (/^prod-[0-9]+$/).`,
+		ValidArgsFunction: completeContexts,
+		SilenceUsage:      true,
+		RunE: func(_ *cobra.Command, args []string) error {
+			if err := validateOutput(output); err != nil {
+				return err
+			}
+			if workers < 1 {
+				return errors.New("--workers must be at least 1")
+			}
+
+			v, err := config.NewCfg().Load()
+			if err != nil {
+				return fmt.Errorf("load config: %w", err)
+			}
+
+			names, err := filterContexts(args)
+			if err != nil {
+				return err
+			}
+			overviews, err := contextOverviews(v, names, workers)
+			if err != nil {
+				return err
+			}
+
+			if output != "" {
+				return printStructured(output, overviews)
+			}
+			printContextOverviews(overviews)
+			return nil
+		},
+	}
+
+	cmd.Flags().IntVar(&workers, "workers", defaultContextWorkers, "number of API servers contacted at the same time")
+	cmd.Flags().StringVarP(&output, "output", "o", "", "output format, valid values are: json, yaml")
+
+	return cmd
+}
+
+// filterContexts returns the names of the kubeconfig contexts matching at
+// least one of the given patterns, all of them when no pattern is given.
+func filterContexts(patterns []string) ([]string, error) {
+	names, err := kubehelper.ContextNames(nil)
+	if err != nil {
+		return nil, fmt.Errorf("read kubeconfig contexts: %w", err)
+	}
+	if len(patterns) == 0 {
+		return names, nil
+	}
+
+	filtered := []string{}
+	for _, name := range names {
+		for _, pattern := range patterns {
+			match, matchErr := config.MatchPattern(pattern, name)
+			if matchErr != nil {
+				return nil, matchErr
+			}
+			if match {
+				filtered = append(filtered, name)
+				break
+			}
+		}
+	}
+
+	return filtered, nil
+}
+
+// loadContextVersions finds the version of the API servers of the given
+// contexts. The versions pinned by the context rules are used as they are.
+func loadContextVersions(v *viper.Viper, names []string, workers int) ([]contextVersion, error) {
+	if _, found := os.LookupEnv(finder.PreventRecursiveInvocationEnvName); found {
+		return nil, errors.New("kuberlr has been invoked while resolving the kubernetes version, the API servers cannot be contacted")
+	}
+
+	results := make([]contextVersion, len(names))
+	for i, name := range names {
+		settings, err := loadKubectlSettings(v, []string{"--context", name})
+		if err != nil {
+			return nil, err
+		}
+		// the version file pins the kubectl used inside of the current
+		// directory, not the one of the contexts
+		settings.VersionFile = nil
+
+		results[i] = contextVersion{Context: name, settings: settings}
+		if info, infoErr := kubehelper.CurrentContext(settings.KubectlArgs); infoErr == nil {
+			results[i].Server = info.Server
+		}
+		if settings.KubectlVersion != "" {
+			// pinned by the context rule, the API server is not contacted
+			results[i].Pinned = true
+			version, parseErr := semver.ParseTolerant(settings.KubectlVersion)
+			if parseErr != nil {
+				results[i].Err = parseErr
+				continue
+			}
+			results[i].Version = &version
+		}
+	}
+
+	if err := queryServerVersions(v, results, workers); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// queryServerVersions finds the version of the API servers of the given
+// contexts, contacting at most `workers` API servers at the same time. The
+// contexts whose version is pinned are skipped.
+func queryServerVersions(v *viper.Viper, results []contextVersion, workers int) error {
+	ttl, err := config.ServerVersionCacheTTL(v)
+	if err != nil {
+		return err
+	}
+	cache, _ := kubehelper.LoadServerVersionCache(kubehelper.DefaultServerVersionCachePath(), ttl)
+	api := kubehelper.KubeAPI{VersionCache: cache}
+
+	// the credential plugins could invoke kubectl, which is kuberlr
+	if err = os.Setenv(finder.PreventRecursiveInvocationEnvName, "1"); err != nil {
+		return err
+	}
+	defer os.Unsetenv(finder.PreventRecursiveInvocationEnvName)
+
+	jobs := make(chan *contextVersion)
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for r := range jobs {
+				version, versionErr := api.Version(r.settings.Timeout, r.settings.KubectlArgs)
+				if versionErr != nil {
+					r.Err = versionErr
+					continue
+				}
+				r.Version = &version
+			}
+		}()
+	}
+
+	for i := range results {
+		if !results[i].Pinned {
+			jobs <- &results[i]
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	return nil
+}
+
+// contextOverviews finds the kubectl that would be used with each one of
+// the given contexts.
+func contextOverviews(v *viper.Viper, names []string, workers int) ([]contextOverview, error) {
+	versions, err := loadContextVersions(v, names, workers)
+	if err != nil {
+		return nil, err
+	}
+
+	current := ""
+	if info, infoErr := kubehelper.CurrentContext(nil); infoErr == nil {
+		current = info.Name
+	}
+
+	kubectlFinder := finder.NewKubectlFinder("", config.SystemPaths(v))
+	overviews := make([]contextOverview, len(versions))
+	for i, cv := range versions {
+		overviews[i] = contextOverview{
+			Context:             cv.Context,
+			Current:             cv.Context == current,
+			Server:              cv.Server,
+			ServerVersionPinned: cv.Pinned,
+		}
+		describeContextKubectl(&overviews[i], cv, kubectlFinder)
+	}
+
+	return overviews, nil
+}
+
+// describeContextKubectl fills the kubectl details of the overview, like
+// kubectlSettings.ensureKubectlAvailable would do, without downloading
+// anything.
+func describeContextKubectl(o *contextOverview, cv contextVersion, kubectlFinder *finder.KubectlFinder) {
+	versioner := cv.settings.newVersioner(kubectlFinder)
+
+	version := cv.Version
+	if version != nil {
+		o.ServerVersion = version.String()
+	} else {
+		o.ServerStatus = kubehelper.ErrorReason(cv.Err)
+		o.ServerError = cv.Err.Error()
+		if cv.Pinned {
+			o.Error = fmt.Sprintf("invalid kubectl version pinned by the context rule: %v", cv.Err)
+			return
+		}
+
+		fallback, err := versioner.FallbackKubectlVersion()
+		if err != nil {
+			o.Error = fmt.Sprintf("cannot find the kubectl version to use: %v", err)
+			return
+		}
+		o.Fallback = true
+		version = &fallback
+	}
+
+	plan := cv.settings.planKubectl(versioner, *version, cv.settings.AllowDownload, false)
+	switch plan.Action {
+	case finder.ActionUse:
+		o.KubectlVersion = plan.Binary.Version.String()
+		o.KubectlPath = plan.Binary.Path
+		o.Download = downloadNotNeeded
+	case finder.ActionDownload:
+		o.KubectlVersion = version.String()
+		o.Download = downloadNeeded
+	default:
+		o.KubectlVersion = version.String()
+		o.Download = downloadDisabled
+	}
+}
+
+//nolint:forbidigo // it's fine to print to stdout
+func printContextOverviews(overviews []contextOverview) {
+	if len(overviews) == 0 {
+		fmt.Println("No kubeconfig context found")
+		return
+	}
+
+	tableWriter := table.NewWriter()
+	tableWriter.SetOutputMirror(os.Stdout)
+	tableWriter.AppendHeader(table.Row{"Current", "Context", "Server", "Server version", "kubectl", "Binary", "Download"})
+	for _, o := range overviews {
+		current := ""
+		if o.Current {
+			current = "*"
+		}
+
+		serverVersion := o.ServerVersion
+		switch {
+		case o.ServerStatus != "":
+			serverVersion = text.FgRed.Sprint(o.ServerStatus)
+		case o.ServerVersionPinned:
+			serverVersion += " (pinned)"
+		}
+
+		kubectlVersion := o.KubectlVersion
+		if o.Fallback && kubectlVersion != "" {
+			kubectlVersion += " (fallback)"
+		}
+
+		binary := o.KubectlPath
+		download := o.Download
+		switch {
+		case o.Error != "":
+			binary = text.FgRed.Sprint(o.Error)
+		case o.Download == downloadNeeded:
+			binary = "-"
+			download = text.FgYellow.Sprint(o.Download)
+		case o.Download == downloadDisabled:
+			binary = "-"
+			download = text.FgRed.Sprint(o.Download)
+		}
+
+		tableWriter.AppendRow(table.Row{current, o.Context, o.Server, serverVersion, kubectlVersion, binary, download})
+	}
+	tableWriter.Render()
+}
@@ -0,0 +1,378 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jedib0t/go-pretty/v6/text"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/flavio/kuberlr/internal/common"
+	"github.com/flavio/kuberlr/internal/config"
+	"github.com/flavio/kuberlr/internal/downloader"
+	"github.com/flavio/kuberlr/internal/finder"
+	"github.com/flavio/kuberlr/internal/kubehelper"
+	"github.com/flavio/kuberlr/internal/osexec"
+)
+
+const (
+	doctorPass = "PASS"
+	doctorWarn = "WARN"
+	doctorFail = "FAIL"
+)
+
+// doctorResult is the outcome of a diagnostic check.
+type doctorResult struct {
+	Check   string
+	Status  string
+	Details string
+	// Hint explains how to fix the problem, it's empty when the check passed
+	Hint string
+}
+
+// NewDoctorCmd creates a new `kuberlr doctor` cobra command.
+func NewDoctorCmd() *cobra.Command {
+	//nolint: forbidigo // it's fine to print to stdout
+	return &cobra.Command{
+		Use:          "doctor",
+		Short:        "Look for common problems of the kuberlr setup",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			results := runDoctorChecks(config.NewCfg())
+
+			failures := 0
+			for _, r := range results {
+				status := text.FgGreen.Sprint(r.Status)
+				switch r.Status {
+				case doctorWarn:
+					status = text.FgYellow.Sprint(r.Status)
+				case doctorFail:
+					status = text.FgRed.Sprint(r.Status)
+					failures++
+				}
+				fmt.Printf("[%s] %s: %s\n", status, r.Check, r.Details)
+				if r.Hint != "" {
+					fmt.Printf("       hint: %s\n", r.Hint)
+				}
+			}
+
+			if failures > 0 {
+				return fmt.Errorf("%d checks failed", failures)
+			}
+			return nil
+		},
+	}
+}
+
+func runDoctorChecks(cfg *config.Cfg) []doctorResult {
+	v, configResult := checkConfigFiles(cfg)
+	results := []doctorResult{
+		configResult,
+		checkShim(),
+		checkDownloadDir(),
+	}
+	results = append(results, checkMirrors(v)...)
+	results = append(results,
+		checkSystemBinaries(v),
+		checkRecursionGuard(),
+	)
+
+	return results
+}
+
+// checkConfigFiles ensures the configuration files can be loaded. The
+// loaded configuration is returned, it holds just the default values when
+// something is wrong.
+func checkConfigFiles(cfg *config.Cfg) (*viper.Viper, doctorResult) {
+	result := doctorResult{Check: "configuration"}
+
+	if cfgEnv, found := os.LookupEnv("KUBERLR_CFG"); found && cfgEnv != "" {
+		if _, err := os.Stat(cfgEnv); err != nil {
+			result.Status = doctorFail
+			result.Details = fmt.Sprintf("KUBERLR_CFG points to %s: %v", cfgEnv, err)
+			result.Hint = "create the file or unset the KUBERLR_CFG environment variable"
+			v, _ := (&config.Cfg{}).Load()
+			return v, result
+		}
+	}
+
+	found := []string{}
+	for _, path := range cfg.Paths {
+		if path == "" {
+			continue
+		}
+		if _, err := os.Stat(path); err == nil {
+			found = append(found, path)
+		}
+	}
+
+	v, err := cfg.Load()
+	if err != nil {
+		result.Status = doctorFail
+		result.Details = fmt.Sprintf("cannot load the configuration: %v", err)
+		result.Hint = "fix the syntax of the configuration files, they are written using the TOML format"
+		v, _ = (&config.Cfg{}).Load()
+		return v, result
+	}
+	if _, err = loadKubectlSettings(v, nil); err != nil {
+		result.Status = doctorFail
+		result.Details = fmt.Sprintf("invalid configuration: %v", err)
+		result.Hint = "fix the values reported above, see kuberlr.conf.example for the valid ones"
+		return v, result
+	}
+
+	result.Status = doctorPass
+	if len(found) == 0 {
+		result.Details = "no configuration file found, using the default values"
+	} else {
+		result.Details = "loaded " + strings.Join(found, ", ")
+	}
+	return v, result
+}
+
+// checkShim ensures the first kubectl found inside of $PATH is kuberlr.
+func checkShim() doctorResult {
+	result := doctorResult{Check: "kubectl shim"}
+	selfPath := executablePath()
+	hint := fmt.Sprintf(
+		"run kuberlr install-shim --dir <directory listed by $PATH>, or create the symlink by hand, e.g.: ln -s %s ~/bin/kubectl",
+		selfPath)
+
+	kubectlName := "kubectl" + osexec.Ext
+	first := ""
+	shim := ""
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		candidate := filepath.Join(dir, kubectlName)
+		info, err := os.Stat(candidate)
+		if err != nil || info.IsDir() {
+			continue
+		}
+		if first == "" {
+			first = candidate
+		}
+		if isKuberlr(candidate, selfPath) {
+			shim = candidate
+			break
+		}
+	}
+
+	switch {
+	case first == "":
+		result.Status = doctorFail
+		result.Details = "no kubectl found inside of $PATH"
+		result.Hint = hint
+	case shim == first:
+		result.Status = doctorPass
+		result.Details = fmt.Sprintf("%s is kuberlr", shim)
+	case shim != "":
+		result.Status = doctorWarn
+		result.Details = fmt.Sprintf("%s comes first in $PATH, the kuberlr shim %s is never used", first, shim)
+		result.Hint = fmt.Sprintf("move %s before %s inside of $PATH, or run kuberlr install-shim --dir %s --move-conflicts",
+			filepath.Dir(shim), filepath.Dir(first), filepath.Dir(shim))
+	default:
+		result.Status = doctorWarn
+		result.Details = fmt.Sprintf("%s is not kuberlr", first)
+		result.Hint = hint
+	}
+
+	return result
+}
+
+// checkDownloadDir ensures kuberlr can save the kubectl binaries it
+// downloads. The directory is not created when missing, its nearest
+// existing ancestor is checked instead.
+func checkDownloadDir() doctorResult {
+	result := doctorResult{Check: "download directory"}
+	dir := common.LocalDownloadDir()
+
+	existing, err := common.NearestExistingDir(dir)
+	if err == nil {
+		err = common.CheckWritable(existing)
+	}
+	if err != nil {
+		result.Status = doctorFail
+		result.Details = fmt.Sprintf("%s is not writable: %v", dir, err)
+		result.Hint = fmt.Sprintf("ensure %s is owned by your user and writable", cmp.Or(existing, dir))
+		return result
+	}
+
+	if existing != dir {
+		result.Status = doctorWarn
+		result.Details = fmt.Sprintf("%s doesn't exist, it will be created inside of %s by the first download", dir, existing)
+		return result
+	}
+
+	result.Status = doctorPass
+	result.Details = fmt.Sprintf("%s is writable", dir)
+	return result
+}
+
+// checkMirrors ensures the mirrors used to download kubectl can be reached,
+// including the ones defined by the context rules.
+func checkMirrors(v *viper.Viper) []doctorResult {
+	mirrors := []string{v.GetString("KubeMirrorUrl")}
+	if rules, err := config.LoadContextRules(v); err == nil {
+		for _, rule := range rules {
+			if rule.KubeMirrorURL != "" && !slices.Contains(mirrors, rule.KubeMirrorURL) {
+				mirrors = append(mirrors, rule.KubeMirrorURL)
+			}
+		}
+	}
+
+	results := []doctorResult{}
+	for _, mirror := range mirrors {
+		result := doctorResult{Check: "mirror " + mirror}
+		d := downloader.Downloder{
+			KubeMirrorURL: mirror,
+			Timeout:       time.Duration(v.GetInt64("Timeout")) * time.Second,
+		}
+
+		version, err := d.UpstreamStableVersion()
+		switch {
+		case err == nil:
+			result.Status = doctorPass
+			result.Details = fmt.Sprintf("reachable, latest stable release is %s", version)
+		case !v.GetBool("AllowDownload"):
+			result.Status = doctorWarn
+			result.Details = fmt.Sprintf("unreachable, downloads are disabled anyway: %v", err)
+			result.Hint = "check your network and proxy settings, or change the KubeMirrorUrl setting"
+		default:
+			result.Status = doctorFail
+			result.Details = fmt.Sprintf("unreachable: %v", err)
+			result.Hint = "check your network and proxy settings, or change the KubeMirrorUrl setting"
+		}
+		results = append(results, result)
+	}
+
+	return results
+}
+
+// checkSystemBinaries looks for system-wide kubectl binaries that are
+// ignored because their version is unknown.
+func checkSystemBinaries(v *viper.Viper) doctorResult {
+	result := doctorResult{Check: "system-wide binaries"}
+	selfPath := executablePath()
+
+	kubectlFinder := finder.NewKubectlFinder("", config.SystemPaths(v))
+	bins, err := kubectlFinder.SystemKubectlBinaries()
+	usable := map[string]bool{}
+	for _, b := range bins {
+		usable[b.Path] = true
+	}
+
+	ignored := []string{}
+	for _, dir := range kubectlFinder.SystemPaths() {
+		entries, readErr := os.ReadDir(dir)
+		if readErr != nil {
+			continue
+		}
+		for _, entry := range entries {
+			name := entry.Name()
+			path := filepath.Join(dir, name)
+			if entry.IsDir() || usable[path] || !finder.LooksLikeKubectl(name) || finder.HasKubectlNamingScheme(name) {
+				continue
+			}
+			if isKuberlr(path, selfPath) {
+				continue
+			}
+			ignored = append(ignored, path)
+		}
+	}
+
+	switch {
+	case err != nil:
+		result.Status = doctorWarn
+		result.Details = fmt.Sprintf("%d usable binaries, some directories cannot be read: %v", len(bins), err)
+		result.Hint = "fix the permissions of the directories or remove them from the SystemPath setting"
+	case len(ignored) > 0:
+		result.Status = doctorWarn
+		result.Details = fmt.Sprintf(
+			"%d usable binaries, these ones are ignored because their version cannot be detected: %s",
+			len(bins), strings.Join(ignored, ", "))
+		result.Hint = "rename them following the kubectl<major>.<minor> or kubectl<major>.<minor>.<patch> naming schemes"
+	default:
+		result.Status = doctorPass
+		result.Details = fmt.Sprintf("%d usable binaries inside of %s",
+			len(bins), strings.Join(kubectlFinder.SystemPaths(), string(os.PathListSeparator)))
+	}
+
+	return result
+}
+
+// checkRecursionGuard looks for the setups that make kuberlr skip the
+// detection of the API server version.
+func checkRecursionGuard() doctorResult {
+	result := doctorResult{Check: "recursion guard"}
+
+	if _, found := os.LookupEnv(finder.PreventRecursiveInvocationEnvName); found {
+		result.Status = doctorWarn
+		result.Details = fmt.Sprintf(
+			"%s is set, kuberlr never contacts the API server and uses the most recent kubectl available",
+			finder.PreventRecursiveInvocationEnvName)
+		result.Hint = "unset the " + finder.PreventRecursiveInvocationEnvName + " environment variable"
+		return result
+	}
+
+	users, err := kubehelper.KubectlExecPlugins(nil)
+	if err != nil {
+		result.Status = doctorWarn
+		result.Details = fmt.Sprintf("cannot read the kubeconfig files: %v", err)
+		result.Hint = "fix the kubeconfig files referenced by $KUBECONFIG or ~/.kube/config"
+		return result
+	}
+	if len(users) > 0 {
+		result.Status = doctorWarn
+		result.Details = fmt.Sprintf(
+			"the credentials of the kubeconfig users %s are obtained by running kubectl, which runs the most recent kubectl available",
+			strings.Join(users, ", "))
+		result.Hint = "ensure the most recent kubectl available supports these credential plugins, or make them run a kubectl binary directly"
+		return result
+	}
+
+	result.Status = doctorPass
+	result.Details = "no recursive invocation of kuberlr expected"
+	return result
+}
+
+// executablePath returns the path of the running kuberlr binary, with all
+// the symlinks resolved.
+func executablePath() string {
+	self, err := os.Executable()
+	if err != nil {
+		return ""
+	}
+	if resolved, err := filepath.EvalSymlinks(self); err == nil {
+		return resolved
+	}
+	return self
+}
+
+// isKuberlr returns true when the given file is kuberlr: either the running
+// binary, a link or a copy of it, or another installation of it.
+func isKuberlr(path, selfPath string) bool {
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return false
+	}
+	if resolved == selfPath || osexec.TrimExt(filepath.Base(resolved)) == "kuberlr" {
+		return true
+	}
+
+	// hardlinks and copies created by install-shim
+	info, err := os.Stat(resolved)
+	if err != nil {
+		return false
+	}
+	if selfInfo, selfErr := os.Stat(selfPath); selfErr == nil && os.SameFile(info, selfInfo) {
+		return true
+	}
+
+	return finder.IsKuberlrBinary(resolved)
+}
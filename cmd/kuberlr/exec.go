@@ -0,0 +1,252 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"k8s.io/klog"
+
+	"github.com/flavio/kuberlr/internal/common"
+	"github.com/flavio/kuberlr/internal/config"
+	"github.com/flavio/kuberlr/internal/finder"
+	"github.com/flavio/kuberlr/internal/osexec"
+)
+
+const (
+	// execDirName is the directory, inside of the kuberlr one, holding the
+	// directories put at the beginning of $PATH by `kuberlr exec` and
+	// `kuberlr env`
+	execDirName = "exec"
+	// execDirKeyLen is the length of the hash naming these directories
+	execDirKeyLen = 16
+	// kubectlVersionEnvName holds the version of the kubectl put inside of
+	// $PATH, like the one set by the shell integration
+	kubectlVersionEnvName = "KUBERLR_KUBECTL"
+)
+
+// envVar is an environment variable set by `kuberlr exec` and `kuberlr env`.
+type envVar struct {
+	Name  string
+	Value string
+}
+
+// NewExecCmd creates a new `kuberlr exec` cobra command.
+func NewExecCmd() *cobra.Command {
+	var kubeContext string
+
+	cmd := &cobra.Command{
+		Use:   "exec [--context name] -- <command> [args...]",
+		Short: "Run a command with the right kubectl at the beginning of $PATH",
+		Long: `Run a command with the right kubectl at the beginning of $PATH.
+
+The kubectl binary is chosen for the current kubeconfig context, or for the
+one given with --context, like kuberlr does when it's invoked as kubectl. A
+directory holding a kubectl symlink pointing to it is then put at the
+beginning of $PATH, so that tools invoking kubectl on their own, like helm or
+terraform, use it. The version of kubectl is available inside of the ` + kubectlVersionEnvName + `
+environment variable.
+
+The directory is kept inside of ` + filepath.Join(common.KuberlrDir(), execDirName) + `, one for each
+kubectl binary, because kuberlr is replaced by the command. It's removed
+together with the kubectl binary, by 'kuberlr rm' and 'kuberlr prune'.
+
+--context only affects the choice of kubectl, the command keeps using the
+kubeconfig context it would use otherwise.`,
+		Example: `
+  Install a helm chart using the kubectl compatible with the prod context:
+  $ kuberlr exec --context prod -- helm install my-release ./chart`,
+		Args:         cobra.MinimumNArgs(1),
+		SilenceUsage: true,
+		RunE: func(_ *cobra.Command, args []string) error {
+			vars, err := kubectlEnv(kubeContext)
+			if err != nil {
+				return err
+			}
+			for _, e := range vars {
+				if err = os.Setenv(e.Name, e.Value); err != nil {
+					return err
+				}
+			}
+
+			// the command is looked for using the new $PATH, hence
+			// `kuberlr exec kubectl` runs the kubectl just chosen
+			path, err := exec.LookPath(args[0])
+			if err != nil {
+				return err
+			}
+			err = osexec.Exec(path, args, os.Environ())
+			return fmt.Errorf("execute %s: %w", path, err)
+		},
+	}
+
+	// the flags of the command are not parsed, even without `--`
+	cmd.Flags().SetInterspersed(false)
+	addKubeContextFlag(cmd, &kubeContext)
+
+	return cmd
+}
+
+// NewEnvCmd creates a new `kuberlr env` cobra command.
+func NewEnvCmd() *cobra.Command {
+	var kubeContext string
+
+	//nolint: forbidigo // it's fine to print to stdout
+	cmd := &cobra.Command{
+		Use:   "env [--context name]",
+		Short: "Print the export lines putting the right kubectl at the beginning of $PATH",
+		Long: `Print the export lines putting the right kubectl at the beginning of $PATH.
+
+The environment variables are the ones set by 'kuberlr exec', they can be
+loaded by the shell with eval or by direnv. The directories previously added
+by kuberlr are removed from $PATH, so the lines can be evaluated again when
+the kubeconfig context changes.`,
+		Example: `
+  Use the kubectl compatible with the current context inside of this shell:
+  $ eval "$(kuberlr env)"
+
+  Add this line to the .envrc file of a project, for direnv:
+  eval "$(kuberlr env --context prod)"`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			vars, err := kubectlEnv(kubeContext)
+			if err != nil {
+				return err
+			}
+			for _, e := range vars {
+				fmt.Printf("export %s=%s\n", e.Name, posixQuote(e.Value))
+			}
+			return nil
+		},
+	}
+
+	addKubeContextFlag(cmd, &kubeContext)
+
+	return cmd
+}
+
+func addKubeContextFlag(cmd *cobra.Command, kubeContext *string) {
+	cmd.Flags().StringVar(kubeContext, "context", "", "kubeconfig context the kubectl binary is chosen for")
+	cmd.RegisterFlagCompletionFunc("context", //nolint: errcheck // the flag has just been defined
+		func(_ *cobra.Command, _ []string, _ string) ([]string, cobra.ShellCompDirective) {
+			return contextNames(nil, nil), cobra.ShellCompDirectiveNoFileComp
+		})
+}
+
+// kubectlEnv finds the kubectl binary to use with the given kubeconfig
+// context, the current one when empty, and returns the environment
+// variables putting it at the beginning of $PATH.
+func kubectlEnv(kubeContext string) ([]envVar, error) {
+	v, err := config.NewCfg().Load()
+	if err != nil {
+		return nil, fmt.Errorf("load config: %w", err)
+	}
+
+	kubectlArgs := []string{}
+	if kubeContext != "" {
+		kubectlArgs = append(kubectlArgs, "--context", kubeContext)
+	}
+	settings, err := loadKubectlSettings(v, kubectlArgs)
+	if err != nil {
+		return nil, fmt.Errorf("load kubectl settings: %w", err)
+	}
+
+	kubectlFinder := finder.NewKubectlFinder("", config.SystemPaths(v))
+	versioner := settings.newVersioner(kubectlFinder)
+	kubectlBin, err := settings.ensureKubectlAvailable(versioner)
+	if err != nil {
+		return nil, fmt.Errorf("ensure compatible kubectl available: %w", err)
+	}
+	if err = finder.RecordUsage(finder.DefaultUsagePath(), common.LocalDownloadDir(), kubectlBin); err != nil {
+		klog.V(common.VerbosityOne).Infof("kuberlr: record usage of %s: %v", kubectlBin, err)
+	}
+
+	if err = removeStaleExecDirs(); err != nil {
+		klog.V(common.VerbosityOne).Infof("cannot remove stale exec directories: %v", err)
+	}
+	dir, err := kubectlExecDir(kubectlBin)
+	if err != nil {
+		return nil, err
+	}
+
+	vars := []envVar{{Name: "PATH", Value: prependPath(dir)}}
+	if version, found := kubectlFinder.KubectlVersion(kubectlBin); found {
+		vars = append(vars, envVar{Name: kubectlVersionEnvName, Value: version.String()})
+	}
+
+	return vars, nil
+}
+
+// kubectlExecDir returns the directory holding a kubectl symlink pointing
+// to the given kubectl binary, creating it when needed. Only symlinks are
+// used, a copy of kubectl would waste space and would keep working after
+// the binary has been removed.
+func kubectlExecDir(kubectlBin string) (string, error) {
+	sum := sha256.Sum256([]byte(kubectlBin))
+	dir := filepath.Join(common.KuberlrDir(), execDirName, hex.EncodeToString(sum[:])[:execDirKeyLen])
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return "", err
+	}
+
+	shimPath := filepath.Join(dir, "kubectl"+osexec.Ext)
+	if shimKind(shimPath, kubectlBin) != shimSymlink {
+		if _, err := createShim(shimPath, kubectlBin, shimSymlink); err != nil {
+			return "", fmt.Errorf("cannot symlink %s inside of %s: %w", kubectlBin, dir, err)
+		}
+	}
+
+	return dir, nil
+}
+
+// removeStaleExecDirs removes the directories created by kubectlExecDir
+// whose kubectl is not a symlink pointing to an existing binary, like after
+// the binary has been removed.
+func removeStaleExecDirs() error {
+	execDir := filepath.Join(common.KuberlrDir(), execDirName)
+	entries, err := os.ReadDir(execDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	var errs []error
+	for _, entry := range entries {
+		dir := filepath.Join(execDir, entry.Name())
+		shimPath := filepath.Join(dir, "kubectl"+osexec.Ext)
+		if info, lstatErr := os.Lstat(shimPath); lstatErr == nil && info.Mode()&os.ModeSymlink != 0 {
+			if _, statErr := os.Stat(shimPath); statErr == nil {
+				continue
+			}
+		}
+		if removeErr := os.RemoveAll(dir); removeErr != nil {
+			errs = append(errs, removeErr)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// prependPath returns the value of $PATH with the given directory at its
+// beginning. The directories added by previous invocations of kuberlr are
+// removed.
+func prependPath(dir string) string {
+	execDir := filepath.Join(common.KuberlrDir(), execDirName) + string(filepath.Separator)
+
+	dirs := []string{dir}
+	for _, d := range filepath.SplitList(os.Getenv("PATH")) {
+		if !strings.HasPrefix(d, execDir) {
+			dirs = append(dirs, d)
+		}
+	}
+
+	return strings.Join(dirs, string(filepath.ListSeparator))
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/flavio/kuberlr/internal/common"
+)
+
+func TestRemoveStaleExecDirs(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("creating symlinks requires special privileges on windows")
+	}
+	t.Setenv(common.HomeDirEnvKey(), t.TempDir())
+
+	kept := filepath.Join(t.TempDir(), "kubectl1.30.2")
+	removed := filepath.Join(t.TempDir(), "kubectl1.29.7")
+	for _, bin := range []string{kept, removed} {
+		require.NoError(t, os.WriteFile(bin, []byte("#!/bin/sh\n"), 0o700))
+	}
+
+	keptDir, err := kubectlExecDir(kept)
+	require.NoError(t, err)
+	removedDir, err := kubectlExecDir(removed)
+	require.NoError(t, err)
+	assert.NotEqual(t, keptDir, removedDir)
+
+	require.NoError(t, os.Remove(removed))
+	require.NoError(t, removeStaleExecDirs())
+
+	assert.DirExists(t, keptDir)
+	assert.NoDirExists(t, removedDir)
+}
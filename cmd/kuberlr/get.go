@@ -1,40 +1,182 @@
 package main
 
 import (
+	"errors"
 	"fmt"
+	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/blang/semver/v4"
 	"github.com/flavio/kuberlr/internal/common"
+	"github.com/flavio/kuberlr/internal/config"
 	"github.com/flavio/kuberlr/internal/downloader"
+	"github.com/flavio/kuberlr/internal/finder"
 	"github.com/spf13/cobra"
 )
 
+const (
+	// namingPatch names the binaries after their full version, like `kubectl1.28.4`
+	namingPatch = "patch"
+	// namingMinor names the binaries after their minor version, like `kubectl1.28`
+	namingMinor = "minor"
+
+	// installedBinaryMode is the mode of the binaries installed outside of
+	// the kuberlr download directory, they can be shared with other users
+	installedBinaryMode = 0o755
+)
+
+// getFlags holds the flags of the `kuberlr get` command.
+type getFlags struct {
+	system bool
+	dest   string
+	naming string
+}
+
 // NewGetCmd creates a new `kuberlr get` cobra command.
 func NewGetCmd() *cobra.Command {
-	return &cobra.Command{
-		Use:          "get [version to get]",
-		Short:        "Download the kubectl version specified",
-		Args:         cobra.ExactArgs(1),
-		SilenceUsage: true,
+	flags := getFlags{}
+
+	cmd := &cobra.Command{
+		Use:   "get [version to get]",
+		Short: "Download the kubectl version specified",
+		Long: `Download the kubectl version specified.
+
+The version can be:
+  - a full version, like 1.28.4 or v1.31.0-rc.1
+  - a minor version, like 1.28: the latest 1.28.x release
+  - stable or latest: the latest stable release, or the latest release
+    including pre-releases
+  - stable-<minor> or latest-<minor>, like stable-1.28: the same, restricted
+    to a minor version
+  - a range, like ">=1.27 <1.29": the most recent stable release inside of it`,
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeRemoteVersions,
+		SilenceUsage:      true,
 		Example: `
-  Download version 1.20.0. Note well: the patch version is automatically inferred:
+  Download the latest 1.20 patch release:
   $ kuberlr get 1.20
-  
+
   Versions can be specified with, or without the 'v' prefix:
-  $ kuberlr get v1.19.1`,
-		RunE: func(_ *cobra.Command, args []string) error {
-			version, err := semver.ParseTolerant(args[0])
+  $ kuberlr get v1.19.1
+
+  Pre-release versions can be downloaded too:
+  $ kuberlr get v1.31.0-rc.1
+
+  Download the latest stable release, or the most recent one inside of a range:
+  $ kuberlr get stable
+  $ kuberlr get ">=1.27 <1.29"
+
+  Install version 1.28.4 for all the users of the system, as kubectl1.28:
+  $ sudo kuberlr get --system --naming minor 1.28.4`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			d := downloader.Downloder{}
+			version, err := d.ResolveVersion(args[0])
 			if err != nil {
-				return fmt.Errorf("invalid version: %w", err)
+				return fmt.Errorf("resolve version %q: %w", args[0], err)
+			}
+			if strings.TrimPrefix(args[0], "v") != version.String() {
+				fmt.Fprintf(os.Stderr, "Resolved %s to version %s\n", args[0], version)
+			}
+
+			if flags.system || flags.dest != "" {
+				return installKubectl(version, flags)
+			}
+			if cmd.Flags().Changed("naming") {
+				return errors.New("--naming can be used only together with --system or --dest")
 			}
 
 			destination := filepath.Join(
 				common.LocalDownloadDir(),
 				common.BuildKubectlNameForLocalBin(version))
 
-			d := downloader.Downloder{}
-			return d.GetKubectlBinary(version, destination)
+			if err = d.GetKubectlBinary(version, destination); err != nil {
+				return err
+			}
+
+			return enforceMaxCacheSize(destination)
 		},
 	}
+
+	cmd.Flags().BoolVar(&flags.system, "system", false,
+		"install kubectl for all the users, inside of the first directory of the SystemPath setting")
+	cmd.Flags().StringVar(&flags.dest, "dest", "",
+		"install kubectl inside of the given directory")
+	cmd.Flags().StringVar(&flags.naming, "naming", namingPatch,
+		fmt.Sprintf("naming scheme used by --system and --dest: %q (kubectl1.28.4) or %q (kubectl1.28)", namingPatch, namingMinor))
+	cmd.MarkFlagsMutuallyExclusive("system", "dest")
+
+	return cmd
+}
+
+// installKubectl downloads the given version of kubectl outside of the
+// kuberlr download directory, making it usable by all the users.
+func installKubectl(version semver.Version, flags getFlags) error {
+	var name string
+	switch flags.naming {
+	case namingPatch:
+		name = common.BuildKubectlNameForLocalBin(version)
+	case namingMinor:
+		name = common.BuildKubectlNameForSystemBin(version)
+	default:
+		return fmt.Errorf("invalid naming scheme %q, must be either %q or %q", flags.naming, namingPatch, namingMinor)
+	}
+
+	dir := flags.dest
+	if flags.system {
+		v, err := config.NewCfg().Load()
+		if err != nil {
+			return fmt.Errorf("load config: %w", err)
+		}
+		systemPaths := config.SystemPaths(v)
+		if len(systemPaths) == 0 {
+			return errors.New("the SystemPath setting doesn't list any directory")
+		}
+		dir = systemPaths[0]
+	}
+
+	if err := common.CheckWritableDir(dir, installedBinaryMode); err != nil {
+		return withPrivilegeHint(err, "cannot install kubectl inside of "+dir)
+	}
+
+	destination := filepath.Join(dir, name)
+	d := downloader.Downloder{}
+	if err := d.GetKubectlBinary(version, destination); err != nil {
+		return err
+	}
+
+	if err := os.Chmod(destination, installedBinaryMode); err != nil {
+		return fmt.Errorf("set mode of %s: %w", destination, err)
+	}
+	if err := common.InheritOwnership(destination, dir); err != nil {
+		return fmt.Errorf("set ownership of %s: %w", destination, err)
+	}
+
+	//nolint: forbidigo // it's fine to print to stdout
+	fmt.Printf("Installed kubectl %s as %s\n", version, destination)
+	return nil
+}
+
+// enforceMaxCacheSize removes the least recently used kubectl binaries
+// downloaded by kuberlr when they exceed the `MaxCacheSize` setting. The
+// binary stored at keepPath is never removed.
+func enforceMaxCacheSize(keepPath string) error {
+	v, err := config.NewCfg().Load()
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+
+	maxSize, err := config.MaxCacheSize(v)
+	if err != nil || maxSize == 0 {
+		return err
+	}
+
+	evicted, err := finder.NewKubectlFinder("", nil).EnforceMaxCacheSize(maxSize, keepPath)
+	for _, b := range evicted {
+		fmt.Fprintf(os.Stderr, "Removed kubectl %s (%s) to honor MaxCacheSize\n", b.Version, b.Path)
+	}
+	if len(evicted) > 0 {
+		err = errors.Join(err, removeStaleExecDirs())
+	}
+	return err
 }
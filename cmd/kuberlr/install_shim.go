@@ -0,0 +1,308 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/jedib0t/go-pretty/v6/text"
+	"github.com/spf13/cobra"
+
+	"github.com/flavio/kuberlr/internal/common"
+	"github.com/flavio/kuberlr/internal/config"
+	"github.com/flavio/kuberlr/internal/finder"
+	"github.com/flavio/kuberlr/internal/osexec"
+)
+
+const (
+	// shimAuto creates a symlink, falling back to a hardlink and then to a
+	// copy when the filesystem doesn't support them
+	shimAuto     = "auto"
+	shimSymlink  = "symlink"
+	shimHardlink = "hardlink"
+	shimCopy     = "copy"
+)
+
+type installShimFlags struct {
+	dir           string
+	mode          string
+	moveConflicts bool
+}
+
+// NewInstallShimCmd creates a new `kuberlr install-shim` cobra command.
+func NewInstallShimCmd() *cobra.Command {
+	flags := installShimFlags{}
+
+	cmd := &cobra.Command{
+		Use:   "install-shim",
+		Short: "Create or repair the kubectl shim pointing to kuberlr",
+		Long: `Create or repair the kubectl shim pointing to kuberlr.
+
+A kubectl symlink pointing to kuberlr is created inside of the given directory,
+the directory of kuberlr by default. A hardlink or a copy of kuberlr is created
+when the filesystem doesn't support symlinks.
+
+The kubectl binaries coming before the shim inside of $PATH are reported as
+conflicts, they would be used instead of kuberlr. With --move-conflicts they
+are moved inside of the first directory of the SystemPath setting, using the
+kubectl<major>.<minor> naming scheme, so that kuberlr can still use them.`,
+		Example: `
+  Create the shim inside of ~/bin:
+  $ kuberlr install-shim --dir ~/bin
+
+  Create the shim inside of /usr/local/bin, moving the kubectl binary
+  installed by the package manager to /usr/bin/kubectl<major>.<minor>:
+  $ sudo kuberlr install-shim --dir /usr/local/bin --move-conflicts`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			return installShim(flags)
+		},
+	}
+
+	cmd.Flags().StringVar(&flags.dir, "dir", "",
+		"directory where the kubectl shim is created, the directory of kuberlr by default")
+	cmd.Flags().StringVar(&flags.mode, "mode", shimAuto,
+		fmt.Sprintf("how the shim is created: %q, %q, %q or %q", shimAuto, shimSymlink, shimHardlink, shimCopy))
+	cmd.Flags().BoolVar(&flags.moveConflicts, "move-conflicts", false,
+		"move the kubectl binaries shadowing the shim inside of the first directory of the SystemPath setting")
+
+	return cmd
+}
+
+//nolint:forbidigo // it's fine to print to stdout
+func installShim(flags installShimFlags) error {
+	if !slices.Contains([]string{shimAuto, shimSymlink, shimHardlink, shimCopy}, flags.mode) {
+		return fmt.Errorf("invalid mode %q, must be one of %q, %q, %q or %q",
+			flags.mode, shimAuto, shimSymlink, shimHardlink, shimCopy)
+	}
+
+	selfPath := executablePath()
+	if selfPath == "" {
+		return errors.New("cannot find the path of the kuberlr binary")
+	}
+	dir := flags.dir
+	if dir == "" {
+		dir = filepath.Dir(selfPath)
+	}
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	shimPath := filepath.Join(dir, "kubectl"+osexec.Ext)
+
+	conflicts := shimConflicts(dir, shimPath, selfPath)
+	if len(conflicts) > 0 {
+		if !flags.moveConflicts {
+			for _, c := range conflicts {
+				fmt.Printf("%s %s would be used instead of kuberlr\n", text.FgRed.Sprint("conflict:"), c)
+			}
+			return errors.New("move the conflicting kubectl binaries, or run again with --move-conflicts")
+		}
+		if err = moveConflicts(conflicts); err != nil {
+			return err
+		}
+	}
+
+	if kind := shimKind(shimPath, selfPath); kind != "" && (flags.mode == shimAuto || flags.mode == kind) {
+		fmt.Printf("%s is already a %s of kuberlr\n", shimPath, kind)
+	} else {
+		if err = common.CheckWritableDir(dir, installedBinaryMode); err != nil {
+			return withPrivilegeHint(err, "cannot create the kubectl shim inside of "+dir)
+		}
+		created, createErr := createShim(shimPath, selfPath, flags.mode)
+		if createErr != nil {
+			return withPrivilegeHint(createErr, "cannot create the kubectl shim "+shimPath)
+		}
+		fmt.Printf("Created %s, a %s of kuberlr\n", shimPath, created)
+	}
+
+	if !slices.Contains(pathDirs(), dir) {
+		fmt.Fprintf(os.Stderr, "%s %s is not inside of $PATH, add it to use the shim\n",
+			text.FgYellow.Sprint("warning:"), dir)
+	}
+	return nil
+}
+
+// shimConflicts returns the kubectl binaries that would be used instead of
+// the shim: the ones inside of the directories listed by $PATH before the
+// one of the shim, plus the shim path itself when it's a real kubectl. When
+// the directory of the shim is not inside of $PATH only the latter is
+// reported, nothing can come before the shim.
+func shimConflicts(dir, shimPath, selfPath string) []string {
+	conflicts := []string{}
+	dirs := pathDirs()
+	if i := slices.Index(dirs, dir); i >= 0 {
+		dirs = dirs[:i]
+	} else {
+		dirs = nil
+	}
+	for _, pathDir := range dirs {
+		candidate := filepath.Join(pathDir, "kubectl"+osexec.Ext)
+		if isRealKubectl(candidate, selfPath) && !slices.Contains(conflicts, candidate) {
+			conflicts = append(conflicts, candidate)
+		}
+	}
+	if isRealKubectl(shimPath, selfPath) && !slices.Contains(conflicts, shimPath) {
+		conflicts = append(conflicts, shimPath)
+	}
+
+	return conflicts
+}
+
+// isRealKubectl returns true when the given file exists and it's not a
+// build of kuberlr.
+func isRealKubectl(path, selfPath string) bool {
+	info, err := os.Stat(path)
+	if err != nil || info.IsDir() {
+		return false
+	}
+
+	return !isKuberlr(path, selfPath)
+}
+
+// moveConflicts moves the given kubectl binaries inside of the first
+// directory of the SystemPath setting, renaming them after their minor
+// version.
+//
+//nolint:forbidigo // it's fine to print to stdout
+func moveConflicts(conflicts []string) error {
+	v, err := config.NewCfg().Load()
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+	systemPaths := config.SystemPaths(v)
+	if len(systemPaths) == 0 {
+		return errors.New("the SystemPath setting doesn't list any directory")
+	}
+	systemDir := systemPaths[0]
+
+	kubectlFinder := finder.NewKubectlFinder("", systemPaths)
+	for _, path := range conflicts {
+		version, found := kubectlFinder.KubectlVersion(path)
+		if !found {
+			return fmt.Errorf("cannot find the version of %s, move it by hand", path)
+		}
+
+		destination := filepath.Join(systemDir, common.BuildKubectlNameForSystemBin(version))
+		if _, statErr := os.Lstat(destination); statErr == nil {
+			return fmt.Errorf("cannot move %s, %s already exists: remove one of them", path, destination)
+		}
+		if err = common.CheckWritableDir(systemDir, installedBinaryMode); err != nil {
+			return withPrivilegeHint(err, "cannot move kubectl binaries inside of "+systemDir)
+		}
+		if err = common.MoveFile(path, destination); err != nil {
+			return withPrivilegeHint(err, "cannot move "+path)
+		}
+		fmt.Printf("Moved kubectl %s from %s to %s\n", version, path, destination)
+	}
+
+	return nil
+}
+
+// shimKind returns how the given shim points to kuberlr, it's empty when
+// the file is missing, is a broken link or isn't kuberlr.
+func shimKind(shimPath, selfPath string) string {
+	info, err := os.Lstat(shimPath)
+	if err != nil {
+		return ""
+	}
+	if info.Mode()&os.ModeSymlink != 0 {
+		if resolved, resolveErr := filepath.EvalSymlinks(shimPath); resolveErr == nil && resolved == selfPath {
+			return shimSymlink
+		}
+		return ""
+	}
+
+	selfInfo, err := os.Stat(selfPath)
+	if err != nil {
+		return ""
+	}
+	if os.SameFile(info, selfInfo) {
+		return shimHardlink
+	}
+	if info.Size() == selfInfo.Size() && sameContent(shimPath, selfPath) {
+		return shimCopy
+	}
+
+	return ""
+}
+
+// createShim replaces the file at shimPath with a link or a copy of
+// kuberlr, returning the kind of shim created.
+func createShim(shimPath, selfPath, mode string) (string, error) {
+	kinds := []string{mode}
+	if mode == shimAuto {
+		kinds = []string{shimSymlink, shimHardlink, shimCopy}
+	}
+
+	// the shim is created with a temporary name and then renamed, this
+	// replaces the previous shim atomically
+	tmpPath := filepath.Join(filepath.Dir(shimPath), fmt.Sprintf(".%s.%d", filepath.Base(shimPath), os.Getpid()))
+	errs := []error{}
+	for _, kind := range kinds {
+		os.Remove(tmpPath)
+
+		var err error
+		switch kind {
+		case shimSymlink:
+			err = os.Symlink(selfPath, tmpPath)
+		case shimHardlink:
+			err = os.Link(selfPath, tmpPath)
+		case shimCopy:
+			if err = common.CopyFile(selfPath, tmpPath); err == nil {
+				err = common.InheritOwnership(tmpPath, filepath.Dir(tmpPath))
+			}
+		}
+		if err == nil {
+			err = os.Rename(tmpPath, shimPath)
+		}
+		if err == nil {
+			return kind, nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", kind, err))
+	}
+	os.Remove(tmpPath)
+
+	return "", errors.Join(errs...)
+}
+
+// sameContent returns true when the two files have the same content.
+func sameContent(a, b string) bool {
+	dataA, err := os.ReadFile(a)
+	if err != nil {
+		return false
+	}
+	dataB, err := os.ReadFile(b)
+	if err != nil {
+		return false
+	}
+
+	return string(dataA) == string(dataB)
+}
+
+// pathDirs returns the absolute paths of the directories listed by $PATH.
+func pathDirs() []string {
+	dirs := []string{}
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		if dir == "" {
+			continue
+		}
+		if abs, err := filepath.Abs(dir); err == nil {
+			dirs = append(dirs, abs)
+		}
+	}
+
+	return dirs
+}
+
+// withPrivilegeHint suggests to run kuberlr with administrative privileges
+// when the error is caused by missing permissions.
+func withPrivilegeHint(err error, msg string) error {
+	if errors.Is(err, os.ErrPermission) {
+		return fmt.Errorf("%s, run kuberlr with administrative privileges (e.g. via sudo): %w", msg, err)
+	}
+	return fmt.Errorf("%s: %w", msg, err)
+}
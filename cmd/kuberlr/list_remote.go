@@ -0,0 +1,233 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/blang/semver/v4"
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/jedib0t/go-pretty/v6/text"
+	"github.com/spf13/cobra"
+	"k8s.io/klog"
+
+	"github.com/flavio/kuberlr/internal/common"
+	"github.com/flavio/kuberlr/internal/config"
+	"github.com/flavio/kuberlr/internal/downloader"
+	"github.com/flavio/kuberlr/internal/finder"
+)
+
+const (
+	// defaultRemoteMinors is how many minor versions `kuberlr list-remote`
+	// shows by default.
+	defaultRemoteMinors = 10
+
+	// defaultRemoteWorkers is how many requests `kuberlr list-remote`
+	// makes to the mirror at the same time by default.
+	defaultRemoteWorkers = 8
+)
+
+// remoteRelease is a kubectl release available on the mirror.
+type remoteRelease struct {
+	Version  string `json:"version"`
+	Platform string `json:"platform"`
+	// Available is false when the mirror doesn't provide the binary for
+	// the platform
+	Available      bool   `json:"available"`
+	AvailableError string `json:"availableError,omitempty"`
+	// Local lists the kubectl binaries of this version found by kuberlr
+	Local []string `json:"local"`
+}
+
+// listRemoteFlags holds the flags of the `kuberlr list-remote` command.
+type listRemoteFlags struct {
+	minor    string
+	minors   int
+	platform string
+	output   string
+	workers  int
+}
+
+// NewListRemoteCmd creates a new `kuberlr list-remote` cobra command.
+func NewListRemoteCmd() *cobra.Command {
+	flags := listRemoteFlags{
+		minors:  defaultRemoteMinors,
+		workers: defaultRemoteWorkers,
+	}
+
+	cmd := &cobra.Command{
+		Use:   "list-remote",
+		Short: "Print the kubectl versions available on the mirror",
+		Long: `Print the kubectl versions available on the mirror set by the KubeMirrorUrl
+setting.
+
+By default the latest release of the most recent minor versions is shown,
+--minor shows all the releases of a minor version instead. The releases are
+cached for one hour inside of ` + downloader.DefaultReleasesCachePath() + `.`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		Example: `
+  Show the latest release of the 10 most recent minor versions:
+  $ kuberlr list-remote
+
+  Show the latest release of all the minor versions:
+  $ kuberlr list-remote --minors 0
+
+  Show all the 1.28 releases available for macOS on Apple silicon, as JSON:
+  $ kuberlr list-remote --minor 1.28 --platform darwin/arm64 -o json`,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			if err := validateOutput(flags.output); err != nil {
+				return err
+			}
+			if flags.minors < 0 {
+				return errors.New("--minors cannot be negative")
+			}
+			if flags.workers < 1 {
+				return errors.New("--workers must be at least 1")
+			}
+
+			releases, err := listRemoteReleases(flags)
+			if err != nil {
+				return err
+			}
+
+			if flags.output != "" {
+				return printStructured(flags.output, releases)
+			}
+			printRemoteReleases(releases)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&flags.minor, "minor", "", "show all the releases of the given minor version, like 1.28")
+	cmd.Flags().IntVar(&flags.minors, "minors", defaultRemoteMinors,
+		"number of minor versions whose latest release is shown, 0 means all of them")
+	cmd.MarkFlagsMutuallyExclusive("minor", "minors")
+	cmd.Flags().StringVar(&flags.platform, "platform", downloader.HostPlatform().String(),
+		"platform of the kubectl binaries, written as <os>/<arch>")
+	cmd.RegisterFlagCompletionFunc("minor", completeMinorVersions) //nolint: errcheck // the flag has just been defined
+	cmd.Flags().StringVarP(&flags.output, "output", "o", "", "output format, valid values are: json, yaml")
+	cmd.Flags().IntVar(&flags.workers, "workers", defaultRemoteWorkers, "number of requests made to the mirror at the same time")
+
+	return cmd
+}
+
+func listRemoteReleases(flags listRemoteFlags) ([]remoteRelease, error) {
+	platform, err := downloader.ParsePlatform(flags.platform)
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := config.NewCfg().Load()
+	if err != nil {
+		return nil, fmt.Errorf("load config: %w", err)
+	}
+	d := downloader.Downloder{
+		KubeMirrorURL: v.GetString("KubeMirrorUrl"),
+		Timeout:       time.Duration(v.GetInt64("Timeout")) * time.Second,
+	}
+
+	cache, err := downloader.LoadReleasesCache(downloader.DefaultReleasesCachePath(), downloader.DefaultReleasesCacheTTL)
+	if err != nil {
+		// the cache is empty, but still usable
+		klog.V(common.VerbosityOne).Infof("ignoring unreadable releases cache: %v", err)
+	}
+
+	var versions []semver.Version
+	if flags.minor != "" {
+		minor, parseErr := semver.ParseTolerant(flags.minor)
+		if parseErr != nil || strings.Count(strings.TrimPrefix(flags.minor, "v"), ".") != 1 {
+			return nil, fmt.Errorf("invalid minor version %q, it must be written like 1.28", flags.minor)
+		}
+		versions, err = cache.MinorReleases(&d, minor.Major, minor.Minor)
+	} else {
+		versions, err = cache.LatestReleases(&d, flags.minors)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("find releases on %s: %w", d.KubeMirrorURL, err)
+	}
+
+	local := map[string][]string{}
+	for _, b := range finder.NewKubectlFinder("", config.SystemPaths(v)).AllKubectlBinaries(true) {
+		local[b.Version.String()] = append(local[b.Version.String()], b.Path)
+	}
+
+	releases := make([]remoteRelease, len(versions))
+	for i, version := range versions {
+		releases[i] = remoteRelease{
+			Version:  version.String(),
+			Platform: platform.String(),
+			Local:    local[version.String()],
+		}
+		if releases[i].Local == nil {
+			releases[i].Local = []string{}
+		}
+	}
+	checkRemoteAvailability(&d, platform, versions, releases, flags.workers)
+
+	return releases, nil
+}
+
+// checkRemoteAvailability asks the mirror whether it provides the binaries
+// of the given versions, making at most `workers` requests at the same time.
+// The result is stored inside of the release with the same index.
+func checkRemoteAvailability(
+	d *downloader.Downloder,
+	platform downloader.Platform,
+	versions []semver.Version,
+	releases []remoteRelease,
+	workers int,
+) {
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				available, availableErr := d.KubectlAvailable(versions[i], platform)
+				releases[i].Available = available
+				if availableErr != nil {
+					releases[i].AvailableError = availableErr.Error()
+				}
+			}
+		}()
+	}
+
+	for i := range versions {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+//nolint:forbidigo // it's fine to print to stdout
+func printRemoteReleases(releases []remoteRelease) {
+	if len(releases) == 0 {
+		fmt.Println("No release found")
+		return
+	}
+
+	tableWriter := table.NewWriter()
+	tableWriter.SetOutputMirror(os.Stdout)
+	tableWriter.AppendHeader(table.Row{"#", "Version", "Platform", "Available", "Local"})
+	for i, r := range releases {
+		available := text.FgGreen.Sprint("yes")
+		switch {
+		case r.AvailableError != "":
+			available = text.FgYellow.Sprint("unknown: " + r.AvailableError)
+		case !r.Available:
+			available = text.FgRed.Sprint("no")
+		}
+
+		local := "-"
+		if len(r.Local) > 0 {
+			local = strings.Join(r.Local, "\n")
+		}
+		tableWriter.AppendRow(table.Row{i + 1, r.Version, r.Platform, available, local})
+	}
+	tableWriter.Render()
+}
@@ -10,6 +10,7 @@ import (
 	"k8s.io/klog"
 
 	"github.com/flavio/kuberlr/cmd/kuberlr/flags"
+	"github.com/flavio/kuberlr/internal/common"
 	"github.com/flavio/kuberlr/internal/config"
 	"github.com/flavio/kuberlr/internal/finder"
 )
@@ -52,6 +53,22 @@ func newRootCmd() *cobra.Command {
 		NewVersionCmd(),
 		NewBinsCmd(),
 		NewGetCmd(),
+		NewCacheCmd(),
+		NewWhichCmd(),
+		NewDoctorCmd(),
+		NewPruneCmd(),
+		NewRmCmd(),
+		NewListRemoteCmd(),
+		NewPrefetchCmd(),
+		NewContextsCmd(),
+		NewConfigCmd(),
+		NewInstallShimCmd(),
+		NewInitCmd(),
+		NewCompletionCmd(),
+		NewExecCmd(),
+		NewEnvCmd(),
+		NewKubectlVersionHookCmd(),
+		NewKubectlCompletionCmd(),
 		NewKubectlWrapperCmd(),
 	)
 
@@ -67,22 +84,22 @@ func kubectlWrapperMode(args []string) {
 		klog.Fatalf("kuberlr: load config: %v", err)
 	}
 
-	kubectlFinder := finder.NewKubectlFinder("", v.GetString("SystemPath"))
-	versioner := finder.NewVersioner(kubectlFinder)
-	version, err := versioner.KubectlVersionToUse(v.GetInt64("Timeout"))
+	settings, err := loadKubectlSettings(v, args)
 	if err != nil {
-		klog.Fatalf("kuberlr: find kubectl version to use: %v", err)
+		klog.Fatalf("kuberlr: load context rules: %v", err)
 	}
 
-	kubectlBin, err := versioner.EnsureCompatibleKubectlAvailable(
-		version,
-		v.GetBool("AllowDownload"),
-		v.GetBool("UseLatestIfNoCompatible"),
-	)
+	kubectlFinder := finder.NewKubectlFinder("", config.SystemPaths(v))
+	versioner := settings.newVersioner(kubectlFinder)
+	kubectlBin, err := settings.ensureKubectlAvailable(versioner)
 	if err != nil {
 		klog.Fatalf("kuberlr: ensure compatible kubectl available: %v", err)
 	}
 
+	if err = finder.RecordUsage(finder.DefaultUsagePath(), common.LocalDownloadDir(), kubectlBin); err != nil {
+		klog.V(common.VerbosityOne).Infof("kuberlr: record usage of %s: %v", kubectlBin, err)
+	}
+
 	childArgs := append([]string{kubectlBin}, args...)
 	err = osexec.Exec(kubectlBin, childArgs, os.Environ())
 	klog.Fatalf("kuberlr: execute kubectl binary located at %s: %v", kubectlBin, err)
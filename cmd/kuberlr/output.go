@@ -0,0 +1,42 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	outputJSON = "json"
+	outputYAML = "yaml"
+)
+
+// validateOutput ensures the given output format is supported. The empty
+// string selects the human readable output.
+func validateOutput(output string) error {
+	switch output {
+	case "", outputJSON, outputYAML:
+		return nil
+	default:
+		return fmt.Errorf("unsupported output format %q, valid values are: %s, %s", output, outputJSON, outputYAML)
+	}
+}
+
+// printStructured prints the given object using the JSON or the YAML
+// format. The field names are the ones of the JSON tags in both cases.
+func printStructured(output string, obj any) error {
+	if output == outputYAML {
+		data, err := yaml.Marshal(obj)
+		if err != nil {
+			return err
+		}
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(obj)
+}
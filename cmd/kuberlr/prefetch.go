@@ -0,0 +1,156 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/jedib0t/go-pretty/v6/text"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/flavio/kuberlr/internal/config"
+	"github.com/flavio/kuberlr/internal/finder"
+	"github.com/flavio/kuberlr/internal/kubehelper"
+)
+
+const (
+	prefetchPresent     = "present"
+	prefetchDownloaded  = "downloaded"
+	prefetchUnreachable = "unreachable"
+	prefetchFailed      = "failed"
+)
+
+// prefetchResult is the outcome of the prefetch of a kubeconfig context.
+type prefetchResult struct {
+	contextVersion
+	Status string
+	Path   string
+}
+
+// NewPrefetchCmd creates a new `kuberlr prefetch` cobra command.
+func NewPrefetchCmd() *cobra.Command {
+	workers := defaultContextWorkers
+
+	cmd := &cobra.Command{
+		Use:   "prefetch",
+		Short: "Download a compatible kubectl for all the kubeconfig contexts",
+		Long: `Download a compatible kubectl for all the kubeconfig contexts.
+
+The API servers of all the contexts are contacted, then the compatible kubectl
+binaries that are missing are downloaded. This ensures kubectl can be used
+later without network access.`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			if workers < 1 {
+				return errors.New("--workers must be at least 1")
+			}
+
+			v, err := config.NewCfg().Load()
+			if err != nil {
+				return fmt.Errorf("load config: %w", err)
+			}
+
+			results, err := prefetchContexts(v, workers)
+			if err != nil {
+				return err
+			}
+			printPrefetchResults(results)
+
+			failures := 0
+			for _, r := range results {
+				if r.Status == prefetchUnreachable || r.Status == prefetchFailed {
+					failures++
+				}
+			}
+			if failures > 0 {
+				return fmt.Errorf("no kubectl available for %d contexts", failures)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().IntVar(&workers, "workers", defaultContextWorkers, "number of API servers contacted at the same time")
+
+	return cmd
+}
+
+// prefetchContexts ensures a compatible kubectl is available for all the
+// contexts of the kubeconfig files.
+func prefetchContexts(v *viper.Viper, workers int) ([]prefetchResult, error) {
+	contexts, err := kubehelper.ContextNames(nil)
+	if err != nil {
+		return nil, fmt.Errorf("read kubeconfig contexts: %w", err)
+	}
+
+	versions, err := loadContextVersions(v, contexts, workers)
+	if err != nil {
+		return nil, err
+	}
+
+	kubectlFinder := finder.NewKubectlFinder("", config.SystemPaths(v))
+	results := make([]prefetchResult, len(versions))
+	for i, cv := range versions {
+		results[i] = prefetchResult{contextVersion: cv}
+		switch {
+		case cv.Version != nil:
+			ensurePrefetched(&results[i], kubectlFinder)
+		case cv.Pinned:
+			results[i].Status = prefetchFailed
+		default:
+			results[i].Status = prefetchUnreachable
+		}
+	}
+
+	return results, nil
+}
+
+// ensurePrefetched ensures a kubectl compatible with the version of the
+// API server of the context, or exactly the version pinned by the context
+// rule, is available, downloading it when needed.
+func ensurePrefetched(r *prefetchResult, kubectlFinder *finder.KubectlFinder) {
+	versioner := r.settings.newVersioner(kubectlFinder)
+
+	plan := r.settings.planKubectl(versioner, *r.Version, r.settings.AllowDownload, false)
+	if plan.Action == finder.ActionUse {
+		r.Status, r.Path = prefetchPresent, plan.Binary.Path
+		return
+	}
+
+	path, err := versioner.Execute(plan)
+	if err != nil {
+		r.Status, r.Err = prefetchFailed, err
+		return
+	}
+	r.Status, r.Path = prefetchDownloaded, path
+}
+
+//nolint:forbidigo // it's fine to print to stdout
+func printPrefetchResults(results []prefetchResult) {
+	if len(results) == 0 {
+		fmt.Println("No kubeconfig context found")
+		return
+	}
+
+	tableWriter := table.NewWriter()
+	tableWriter.SetOutputMirror(os.Stdout)
+	tableWriter.AppendHeader(table.Row{"#", "Context", "Server", "Version", "Status", "kubectl"})
+	for i, r := range results {
+		version := "-"
+		if r.Version != nil {
+			version = r.Version.String()
+		}
+
+		status := text.FgGreen.Sprint(r.Status)
+		details := r.Path
+		if r.Err != nil {
+			status = text.FgRed.Sprint(r.Status)
+			details = r.Err.Error()
+		}
+
+		tableWriter.AppendRow(table.Row{i + 1, r.Context, r.Server, version, status, details})
+	}
+	tableWriter.Render()
+}
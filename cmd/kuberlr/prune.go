@@ -0,0 +1,226 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/flavio/kuberlr/internal/common"
+	"github.com/flavio/kuberlr/internal/config"
+	"github.com/flavio/kuberlr/internal/finder"
+	"github.com/flavio/kuberlr/internal/kubehelper"
+)
+
+// day is the unit of the durations given with the `d` suffix.
+const day = 24 * time.Hour
+
+// pruneFlags holds the flags of the `kuberlr prune` command.
+type pruneFlags struct {
+	olderThan          string
+	keepLatestPerMinor bool
+	keepUsedByContexts bool
+	all                bool
+	dryRun             bool
+}
+
+// NewPruneCmd creates a new `kuberlr prune` cobra command.
+func NewPruneCmd() *cobra.Command {
+	flags := pruneFlags{}
+
+	cmd := &cobra.Command{
+		Use:   "prune",
+		Short: "Remove the kubectl binaries downloaded by kuberlr",
+		Long: `Remove the kubectl binaries downloaded by kuberlr.
+
+The binaries to remove must be selected explicitly: --all selects all of them,
+--older-than the ones not used recently. The --keep-* flags only exclude
+binaries from the selection. System-wide binaries are never removed. Removed
+binaries are downloaded again when needed.`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		Example: `
+  Preview the removal of the binaries not used during the last 30 days:
+  $ kuberlr prune --older-than 30d --dry-run
+
+  Keep only the most recent patch release of each minor version, and the
+  binaries used by the kubeconfig contexts:
+  $ kuberlr prune --all --keep-latest-per-minor --keep-used-by-contexts
+
+  Remove all the downloaded binaries:
+  $ kuberlr prune --all`,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			return runPrune(flags)
+		},
+	}
+
+	cmd.Flags().StringVar(&flags.olderThan, "older-than", "",
+		"remove the binaries not used for longer than this duration, like 720h or 30d")
+	cmd.Flags().BoolVar(&flags.keepLatestPerMinor, "keep-latest-per-minor", false,
+		"keep the most recent patch release of each minor version")
+	cmd.Flags().BoolVar(&flags.keepUsedByContexts, "keep-used-by-contexts", false,
+		"keep the binaries kuberlr picks for the kubeconfig contexts, based on the last known version of their API servers")
+	cmd.Flags().BoolVar(&flags.all, "all", false,
+		"remove all the downloaded binaries, unless kept by the other flags")
+	cmd.Flags().BoolVar(&flags.dryRun, "dry-run", false,
+		"print the binaries that would be removed without removing them")
+
+	return cmd
+}
+
+//nolint:forbidigo // it's fine to print to stdout
+func runPrune(flags pruneFlags) error {
+	if !flags.all && flags.olderThan == "" {
+		return errors.New("no binary selected: use --all to remove all the downloaded binaries, " +
+			"or --older-than to remove the ones not used recently; the --keep-* flags only exclude binaries from the removal")
+	}
+
+	opts := finder.PruneOptions{
+		KeepLatestPerMinor: flags.keepLatestPerMinor,
+		Keep:               map[string]bool{},
+	}
+	if flags.olderThan != "" {
+		olderThan, err := parseAge(flags.olderThan)
+		if err != nil {
+			return err
+		}
+		opts.OlderThan = olderThan
+	}
+
+	v, err := config.NewCfg().Load()
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+
+	kubectlFinder := finder.NewKubectlFinder("", config.SystemPaths(v))
+	bins, err := kubectlFinder.LocalKubectlBinaries()
+	if err != nil {
+		return fmt.Errorf("find local kubectl binaries: %w", err)
+	}
+
+	if flags.keepUsedByContexts {
+		used, usedErr := binariesUsedByContexts(v, kubectlFinder)
+		if usedErr != nil {
+			return usedErr
+		}
+		for path, contexts := range used {
+			fmt.Printf("Keeping %s, used by contexts %s\n", path, strings.Join(contexts, ", "))
+			opts.Keep[path] = true
+		}
+	}
+
+	plan := finder.PlanPrune(bins, opts, time.Now())
+	if len(plan) == 0 {
+		fmt.Println("No kubectl binary to remove")
+		return nil
+	}
+
+	action := "Removed"
+	if flags.dryRun {
+		action = "Would remove"
+	}
+
+	var total int64
+	tableWriter := table.NewWriter()
+	tableWriter.SetOutputMirror(os.Stdout)
+	tableWriter.AppendHeader(table.Row{"#", "Version", "Binary", "Last used", "Size"})
+	for i, b := range plan {
+		lastUsed := "never"
+		if !b.LastUsed.IsZero() {
+			lastUsed = b.LastUsed.Local().Format(time.RFC3339)
+		}
+		var size int64
+		if info, statErr := os.Stat(b.Path); statErr == nil {
+			size = info.Size()
+		}
+		total += size
+		tableWriter.AppendRow(table.Row{i + 1, b.Version, b.Path, lastUsed, common.FormatSize(size)})
+	}
+
+	if !flags.dryRun {
+		err = errors.Join(kubectlFinder.RemoveKubectlBinaries(plan), removeStaleExecDirs())
+	}
+
+	fmt.Printf("%s %d kubectl binaries, %s\n", action, len(plan), common.FormatSize(total))
+	tableWriter.Render()
+
+	return err
+}
+
+// binariesUsedByContexts returns the local kubectl binaries picked for the
+// contexts of the kubeconfig files, mapped to the names of the contexts.
+// The version of the API servers is taken from the context rules or from
+// the server version cache, the API servers are never contacted.
+func binariesUsedByContexts(v *viper.Viper, kubectlFinder *finder.KubectlFinder) (map[string][]string, error) {
+	contexts, err := kubehelper.ContextNames(nil)
+	if err != nil {
+		return nil, fmt.Errorf("read kubeconfig contexts: %w", err)
+	}
+
+	used := map[string][]string{}
+	unknown := []string{}
+	for _, name := range contexts {
+		kubectlArgs := []string{"--context", name}
+		settings, settingsErr := loadKubectlSettings(v, kubectlArgs)
+		if settingsErr != nil {
+			return nil, settingsErr
+		}
+		// the version file pins the kubectl used inside of the current
+		// directory, not the one of the contexts
+		settings.VersionFile = nil
+
+		version, found, versionErr := contextServerVersion(settings)
+		if versionErr != nil {
+			return nil, fmt.Errorf("find the version of the API server of context %q: %w", name, versionErr)
+		}
+		if !found {
+			unknown = append(unknown, name)
+			continue
+		}
+
+		plan := settings.planKubectl(settings.newVersioner(kubectlFinder), version, false, false)
+		if plan.Action == finder.ActionUse && plan.Binary.Origin == finder.OriginLocal {
+			used[plan.Binary.Path] = append(used[plan.Binary.Path], name)
+		}
+	}
+
+	// removing the kubectl of these contexts would go unnoticed until the
+	// next time they are used
+	if len(unknown) > 0 {
+		return nil, fmt.Errorf(
+			"the version of the API server of contexts %s is unknown, nothing has been removed: "+
+				"run `kuberlr contexts` to find it, or do not use --keep-used-by-contexts",
+			strings.Join(unknown, ", "))
+	}
+
+	for path := range used {
+		sort.Strings(used[path])
+	}
+
+	return used, nil
+}
+
+// parseAge parses a positive duration like `720h`, also accepting a number
+// of days like `30d`.
+func parseAge(value string) (time.Duration, error) {
+	if days, found := strings.CutSuffix(value, "d"); found {
+		n, err := strconv.ParseFloat(days, 64)
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("invalid duration %q", value)
+		}
+		return time.Duration(n * float64(day)), nil
+	}
+
+	age, err := time.ParseDuration(value)
+	if err != nil || age <= 0 {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+	return age, nil
+}
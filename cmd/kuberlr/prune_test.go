@@ -0,0 +1,36 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunPruneRequiresSelection(t *testing.T) {
+	for _, flags := range []pruneFlags{
+		{},
+		{keepLatestPerMinor: true},
+		{keepUsedByContexts: true, dryRun: true},
+	} {
+		err := runPrune(flags)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "no binary selected")
+	}
+}
+
+func TestParseAge(t *testing.T) {
+	age, err := parseAge("30d")
+	require.NoError(t, err)
+	assert.Equal(t, 30*day, age)
+
+	age, err = parseAge("36h")
+	require.NoError(t, err)
+	assert.Equal(t, 36*time.Hour, age)
+
+	for _, value := range []string{"0d", "0s", "-1d", "-5h", "30", "d"} {
+		_, err = parseAge(value)
+		assert.Error(t, err, value)
+	}
+}
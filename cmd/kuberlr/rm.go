@@ -0,0 +1,122 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/flavio/kuberlr/internal/common"
+	"github.com/flavio/kuberlr/internal/config"
+	"github.com/flavio/kuberlr/internal/finder"
+)
+
+// NewRmCmd creates a new `kuberlr rm` cobra command.
+func NewRmCmd() *cobra.Command {
+	var system bool
+
+	//nolint: forbidigo // it's fine to print to stdout
+	cmd := &cobra.Command{
+		Use:               "rm <version|range>...",
+		Short:             "Remove the kubectl binaries matching the given versions",
+		Args:              cobra.MinimumNArgs(1),
+		ValidArgsFunction: completeLocalVersions,
+		SilenceUsage:      true,
+		Example: `
+  Remove kubectl 1.28.4:
+  $ kuberlr rm 1.28.4
+
+  Remove all the 1.27 and 1.28 releases:
+  $ kuberlr rm 1.27 1.28
+
+  Remove all the releases older than 1.29, including the system-wide ones:
+  $ kuberlr rm --system "<1.29"`,
+		RunE: func(_ *cobra.Command, args []string) error {
+			ranges := make([]*common.VersionRange, len(args))
+			for i, arg := range args {
+				versionRange, err := common.ParseVersionRange(arg)
+				if err != nil {
+					return fmt.Errorf("invalid version or range %q: %w", arg, err)
+				}
+				ranges[i] = versionRange
+			}
+
+			v, err := config.NewCfg().Load()
+			if err != nil {
+				return fmt.Errorf("load config: %w", err)
+			}
+			// the directories added by ScanPATH hold binaries installed by
+			// other tools, like package managers, kuberlr must not remove them
+			kubectlFinder := finder.NewKubectlFinder("", config.ConfiguredSystemPaths(v))
+
+			bins, err := kubectlFinder.LocalKubectlBinaries()
+			if err != nil {
+				return fmt.Errorf("find local kubectl binaries: %w", err)
+			}
+			systemBins, err := kubectlFinder.SystemKubectlBinaries()
+			if err != nil && system {
+				return fmt.Errorf("find system-wide kubectl binaries: %w", err)
+			}
+			bins = append(bins, systemBins...)
+
+			toRemove := finder.KubectlBinaries{}
+			var errs []error
+			for i, versionRange := range ranges {
+				matched, skipped := 0, 0
+				for _, b := range bins {
+					if !versionRange.Contains(b.Version) {
+						continue
+					}
+					if b.Origin == finder.OriginSystem && !system {
+						skipped++
+						continue
+					}
+					matched++
+					if !containsBinary(toRemove, b) {
+						toRemove = append(toRemove, b)
+					}
+				}
+
+				switch {
+				case matched == 0 && skipped > 0:
+					errs = append(errs, fmt.Errorf(
+						"%q matches only system-wide kubectl binaries, use --system to remove them", args[i]))
+				case matched == 0:
+					errs = append(errs, fmt.Errorf("no kubectl binary matches %q", args[i]))
+				case skipped > 0:
+					fmt.Fprintf(os.Stderr,
+						"Ignoring %d system-wide kubectl binaries matching %q, use --system to remove them\n",
+						skipped, args[i])
+				}
+			}
+
+			if err = kubectlFinder.RemoveKubectlBinaries(toRemove); err != nil {
+				errs = append(errs, err)
+			}
+			if err = removeStaleExecDirs(); err != nil {
+				errs = append(errs, err)
+			}
+			for _, b := range toRemove {
+				if _, statErr := os.Lstat(b.Path); os.IsNotExist(statErr) {
+					fmt.Printf("Removed kubectl %s (%s)\n", b.Version, b.Path)
+				}
+			}
+
+			return errors.Join(errs...)
+		},
+	}
+
+	cmd.Flags().BoolVar(&system, "system", false, "remove also the matching kubectl binaries found inside of the SystemPath directories")
+
+	return cmd
+}
+
+func containsBinary(bins finder.KubectlBinaries, bin finder.KubectlBinary) bool {
+	for _, b := range bins {
+		if b.Path == bin.Path {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/blang/semver/v4"
+	"github.com/spf13/viper"
+	"k8s.io/klog"
+
+	"github.com/flavio/kuberlr/internal/common"
+	"github.com/flavio/kuberlr/internal/config"
+	"github.com/flavio/kuberlr/internal/finder"
+	"github.com/flavio/kuberlr/internal/kubehelper"
+)
+
+const (
+	// versionSourceServer means the version of the API server is used
+	versionSourceServer = "api-server"
+	// versionSourceFallback means the API server could not be contacted
+	versionSourceFallback = "fallback"
+	// versionSourceVersionFile means the version is pinned by a `.kubectl-version` file
+	versionSourceVersionFile = "version-file"
+	// versionSourceContextRule means the version is pinned by a context rule
+	versionSourceContextRule = "context-rule"
+)
+
+// requestedVersion is the version returned by kubectlVersionToUse.
+type requestedVersion struct {
+	Version semver.Version
+	// Source tells where the version comes from, it's one of the
+	// versionSource constants
+	Source string
+	// ServerErr is set when the version of the API server cannot be found,
+	// Version is then the fallback one
+	ServerErr error
+}
+
+// kubectlSettings holds the settings used to pick the kubectl binary, once
+// the rule matching the current kubeconfig context has been applied.
+type kubectlSettings struct {
+	// KubectlArgs are the arguments given to kubectl, without the name
+	// of the binary
+	KubectlArgs             []string
+	VersionFile             *finder.VersionFile
+	Context                 kubehelper.ContextInfo
+	Rule                    *config.ContextRule
+	KubectlVersion          string
+	AllowDownload           bool
+	UseLatestIfNoCompatible bool
+	KubeMirrorURL           string
+	SkewPolicy              finder.SkewPolicy
+	RankingPreferences      finder.RankingPreferences
+	Timeout                 int64
+	ServerVersionCacheTTL   time.Duration
+	// MaxCacheSize is the maximum size, in bytes, of the downloaded
+	// kubectl binaries, zero means no limit
+	MaxCacheSize int64
+}
+
+// loadKubectlSettings reads the global settings and then applies the
+// context rule matching the kubeconfig context used by kubectl when invoked
+// with the given arguments, if any. It also looks for a `.kubectl-version`
+// file starting from the working directory.
+func loadKubectlSettings(v *viper.Viper, kubectlArgs []string) (kubectlSettings, error) {
+	settings := kubectlSettings{
+		KubectlArgs:             kubectlArgs,
+		AllowDownload:           v.GetBool("AllowDownload"),
+		UseLatestIfNoCompatible: v.GetBool("UseLatestIfNoCompatible"),
+		KubeMirrorURL:           v.GetString("KubeMirrorUrl"),
+		Timeout:                 v.GetInt64("Timeout"),
+		// never nil, an empty list disables the ranking
+		RankingPreferences: append(finder.RankingPreferences{}, v.GetStringSlice("Ranking")...),
+	}
+	if err := settings.RankingPreferences.Validate(); err != nil {
+		return settings, err
+	}
+	ttl, err := config.ServerVersionCacheTTL(v)
+	if err != nil {
+		return settings, err
+	}
+	settings.ServerVersionCacheTTL = ttl
+	if settings.MaxCacheSize, err = config.MaxCacheSize(v); err != nil {
+		return settings, err
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return settings, err
+	}
+	versionFile, found, err := finder.FindVersionFile(cwd)
+	if err != nil {
+		return settings, err
+	}
+	if found {
+		klog.V(common.VerbosityTwo).Infof("kubectl version pinned to %q by %s", versionFile.Constraint, versionFile.Path)
+		settings.VersionFile = &versionFile
+	}
+
+	policyName := v.GetString("SkewPolicy")
+	minorsBelow := v.GetUint64("SkewMinorsBelow")
+	minorsAbove := v.GetUint64("SkewMinorsAbove")
+
+	settings.Context, settings.Rule, err = matchContextRule(v, kubectlArgs)
+	if err != nil {
+		return settings, err
+	}
+	if rule := settings.Rule; rule != nil {
+		settings.KubectlVersion = rule.KubectlVersion
+		if rule.AllowDownload != nil {
+			settings.AllowDownload = *rule.AllowDownload
+		}
+		if rule.KubeMirrorURL != "" {
+			settings.KubeMirrorURL = rule.KubeMirrorURL
+		}
+		if rule.SkewPolicy != "" {
+			policyName = rule.SkewPolicy
+		}
+		if rule.SkewMinorsBelow != nil {
+			minorsBelow = *rule.SkewMinorsBelow
+		}
+		if rule.SkewMinorsAbove != nil {
+			minorsAbove = *rule.SkewMinorsAbove
+		}
+	}
+
+	settings.SkewPolicy, err = finder.NewSkewPolicy(policyName, minorsBelow, minorsAbove)
+	return settings, err
+}
+
+// matchContextRule returns the kubeconfig context in use and the context
+// rule matching it. The rule is nil when nothing matches. The kubeconfig
+// files are not read when no rule is defined.
+func matchContextRule(v *viper.Viper, kubectlArgs []string) (kubehelper.ContextInfo, *config.ContextRule, error) {
+	rules, err := config.LoadContextRules(v)
+	if err != nil {
+		return kubehelper.ContextInfo{}, nil, err
+	}
+	if len(rules) == 0 {
+		return kubehelper.ContextInfo{}, nil, nil
+	}
+
+	contextInfo, err := kubehelper.CurrentContext(kubectlArgs)
+	if err != nil {
+		klog.V(common.VerbosityOne).Infof("cannot find the kubeconfig context in use: %v", err)
+		return kubehelper.ContextInfo{}, nil, nil
+	}
+
+	rule, found := rules.Match(contextInfo.Name, contextInfo.Server)
+	if !found {
+		return contextInfo, nil, nil
+	}
+	klog.V(common.VerbosityTwo).Infof("context %q matched by rule %s", contextInfo.Name, rule)
+
+	return contextInfo, &rule, nil
+}
+
+// newVersioner returns a Versioner configured with these settings.
+func (s kubectlSettings) newVersioner(f *finder.KubectlFinder) *finder.Versioner {
+	versioner := finder.NewVersioner(f)
+	versioner.SetSkewPolicy(s.SkewPolicy)
+	versioner.SetRankingPreferences(s.RankingPreferences)
+	versioner.SetMaxCacheSize(s.MaxCacheSize)
+	if s.Rule != nil && s.Rule.KubeMirrorURL != "" {
+		versioner.SetKubeMirrorURL(s.KubeMirrorURL)
+	}
+
+	cache, err := kubehelper.LoadServerVersionCache(kubehelper.DefaultServerVersionCachePath(), s.ServerVersionCacheTTL)
+	if err != nil {
+		klog.V(common.VerbosityOne).Infof("ignoring unreadable server version cache: %v", err)
+	}
+	versioner.SetServerVersionCache(cache)
+
+	return versioner
+}
+
+// ensureKubectlAvailable returns the path to the kubectl binary to use,
+// downloading it when needed and allowed.
+func (s kubectlSettings) ensureKubectlAvailable(versioner *finder.Versioner) (string, error) {
+	if s.VersionFile != nil && s.VersionFile.Range != nil {
+		return versioner.EnsureKubectlInRangeAvailable(s.VersionFile.Range, s.AllowDownload, s.UseLatestIfNoCompatible)
+	}
+
+	requested, err := s.kubectlVersionToUse(versioner)
+	if err != nil {
+		return "", fmt.Errorf("find kubectl version to use: %w", err)
+	}
+
+	return versioner.Execute(s.planKubectl(versioner, requested.Version, s.AllowDownload, s.UseLatestIfNoCompatible))
+}
+
+// kubectlVersionToUse returns the version pinned by the `.kubectl-version`
+// file or by the context rule. When nothing is pinned, the version computed
+// by the versioner is returned. The version is returned together with its
+// source, also when an error occurs.
+func (s kubectlSettings) kubectlVersionToUse(versioner *finder.Versioner) (requestedVersion, error) {
+	if s.VersionFile != nil && s.VersionFile.Version != nil {
+		return requestedVersion{Version: *s.VersionFile.Version, Source: versionSourceVersionFile}, nil
+	}
+	if s.KubectlVersion != "" {
+		klog.V(common.VerbosityTwo).Infof("using kubectl version %s pinned by rule %s", s.KubectlVersion, s.Rule)
+		version, err := semver.ParseTolerant(s.KubectlVersion)
+		return requestedVersion{Version: version, Source: versionSourceContextRule}, err
+	}
+
+	version, serverErr, err := versioner.ResolveKubectlVersion(s.Timeout, s.KubectlArgs)
+	requested := requestedVersion{Version: version, Source: versionSourceServer, ServerErr: serverErr}
+	if serverErr != nil {
+		requested.Source = versionSourceFallback
+	}
+	return requested, err
+}
+
+// pinnedExactly returns true when the version returned by
+// kubectlVersionToUse is a full version pinned by the `.kubectl-version`
+// file or by the context rule. Such a version is the version of kubectl,
+// not the one of the API server.
+func (s kubectlSettings) pinnedExactly() bool {
+	if s.VersionFile != nil {
+		return s.VersionFile.Version != nil
+	}
+	return s.KubectlVersion != ""
+}
+
+// planKubectl plans the usage of the kubectl binary for the version returned
+// by kubectlVersionToUse: exactly that version when it's pinned, otherwise a
+// kubectl compatible with it.
+func (s kubectlSettings) planKubectl(
+	versioner *finder.Versioner,
+	version semver.Version,
+	allowDownload bool,
+	useLatestIfNoCompatible bool,
+) finder.Plan {
+	if s.pinnedExactly() {
+		return versioner.PlanExact(version, allowDownload, useLatestIfNoCompatible)
+	}
+
+	return versioner.PlanCompatible(version, allowDownload, useLatestIfNoCompatible)
+}
+
+// contextServerVersion returns the kubectl version pinned by the context
+// rule or, when nothing is pinned, the last known version of the API server.
+func contextServerVersion(settings kubectlSettings) (semver.Version, bool, error) {
+	if settings.KubectlVersion != "" {
+		version, err := semver.ParseTolerant(settings.KubectlVersion)
+		return version, err == nil, err
+	}
+
+	cache, err := kubehelper.LoadServerVersionCache(kubehelper.DefaultServerVersionCachePath(), settings.ServerVersionCacheTTL)
+	if err != nil {
+		return semver.Version{}, false, err
+	}
+	api := kubehelper.KubeAPI{VersionCache: cache}
+
+	return api.CachedVersion(settings.KubectlArgs)
+}
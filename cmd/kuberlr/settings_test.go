@@ -0,0 +1,115 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/blang/semver/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/flavio/kuberlr/internal/common"
+	"github.com/flavio/kuberlr/internal/finder"
+)
+
+func TestPlanKubectlVersionFile(t *testing.T) {
+	t.Setenv(common.HomeDirEnvKey(), t.TempDir())
+
+	localDir := t.TempDir()
+	// compatible with 1.28.4, but not the pinned version
+	require.NoError(t, os.WriteFile(filepath.Join(localDir, "kubectl1.28.7"), []byte("#!/bin/sh\n"), 0o700))
+	kubectlFinder := finder.NewKubectlFinder(localDir, []string{t.TempDir()})
+
+	policy, err := finder.NewSkewPolicy("upstream", 1, 1)
+	require.NoError(t, err)
+	pinned := semver.MustParse("1.28.4")
+
+	tests := []struct {
+		name           string
+		settings       kubectlSettings
+		expectedAction string
+		expectedPath   string
+	}{
+		{
+			name:           "full version",
+			settings:       kubectlSettings{VersionFile: &finder.VersionFile{Version: &pinned}},
+			expectedAction: finder.ActionDownload,
+			expectedPath:   common.BuildKubectlNameForLocalBin(pinned),
+		},
+		{
+			name:           "context rule",
+			settings:       kubectlSettings{KubectlVersion: "1.28.4"},
+			expectedAction: finder.ActionDownload,
+			expectedPath:   common.BuildKubectlNameForLocalBin(pinned),
+		},
+		{
+			name:           "API server version",
+			settings:       kubectlSettings{},
+			expectedAction: finder.ActionUse,
+			expectedPath:   filepath.Join(localDir, "kubectl1.28.7"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.settings.SkewPolicy = policy
+			versioner := tt.settings.newVersioner(kubectlFinder)
+
+			plan := tt.settings.planKubectl(versioner, pinned, true, false)
+
+			assert.Equal(t, tt.expectedAction, plan.Action)
+			assert.Contains(t, plan.Binary.Path, tt.expectedPath)
+		})
+	}
+}
+
+func TestKubectlVersionToUseSource(t *testing.T) {
+	t.Setenv(common.HomeDirEnvKey(), t.TempDir())
+	t.Setenv("KUBECONFIG", filepath.Join(t.TempDir(), "missing"))
+
+	localDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(localDir, "kubectl1.28.7"), []byte("#!/bin/sh\n"), 0o700))
+	kubectlFinder := finder.NewKubectlFinder(localDir, []string{t.TempDir()})
+	pinned := semver.MustParse("1.28.4")
+
+	tests := []struct {
+		name            string
+		settings        kubectlSettings
+		expectedSource  string
+		expectedVersion string
+	}{
+		{
+			name:            "version file",
+			settings:        kubectlSettings{VersionFile: &finder.VersionFile{Version: &pinned}},
+			expectedSource:  versionSourceVersionFile,
+			expectedVersion: "1.28.4",
+		},
+		{
+			name:            "context rule",
+			settings:        kubectlSettings{KubectlVersion: "v1.29.1"},
+			expectedSource:  versionSourceContextRule,
+			expectedVersion: "1.29.1",
+		},
+		{
+			name: "unreachable API server",
+			settings: kubectlSettings{
+				KubectlArgs: []string{"--server", "https://127.0.0.1:1"},
+				Timeout:     1,
+			},
+			expectedSource:  versionSourceFallback,
+			expectedVersion: "1.28.7",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requested, err := tt.settings.kubectlVersionToUse(tt.settings.newVersioner(kubectlFinder))
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.expectedSource, requested.Source)
+			assert.Equal(t, tt.expectedVersion, requested.Version.String())
+			assert.Equal(t, tt.expectedSource == versionSourceFallback, requested.ServerErr != nil)
+		})
+	}
+}
@@ -0,0 +1,434 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/blang/semver/v4"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/flavio/kuberlr/internal/common"
+	"github.com/flavio/kuberlr/internal/config"
+	"github.com/flavio/kuberlr/internal/finder"
+	"github.com/flavio/kuberlr/internal/kubehelper"
+	"github.com/flavio/kuberlr/internal/osexec"
+)
+
+const (
+	// initModeFunction defines a kubectl shell function invoking kuberlr
+	initModeFunction = "function"
+	// initModePath prepends a directory holding the kubectl shim to $PATH
+	initModePath = "path"
+
+	// kubectlVersionHookCmd is the hidden command used by the prompt hook
+	kubectlVersionHookCmd = "__kubectl-version"
+	// kubectlCompletionCmd is the hidden command printing the completion
+	// script of kubectl
+	kubectlCompletionCmd = "__kubectl-completion"
+	// hookKeyLen is the number of hex digits of the key identifying the
+	// state the active kubectl depends on
+	hookKeyLen = 16
+)
+
+// shellInit holds the snippets of the script generated for a shell. The
+// `{{kuberlr}}`, `{{dir}}` and `{{kubectl}}` placeholders are replaced by
+// the quoted path of kuberlr, of the shim directory and of the shim.
+type shellInit struct {
+	quote      func(string) string
+	function   string
+	path       string
+	completion func(root *cobra.Command, buf *bytes.Buffer) error
+	kubectl    string
+	prompt     string
+}
+
+var shellInits = map[string]shellInit{ //nolint: gochecknoglobals // maps cannot be go constants
+	"bash": {
+		quote: posixQuote,
+		function: `kubectl() {
+  command {{kuberlr}} kubectl "$@"
+}
+`,
+		path: `case ":$PATH:" in
+  *:{{dir}}:*) ;;
+  *) export PATH={{dir}}":$PATH" ;;
+esac
+[ -e {{kubectl}} ] || command {{kuberlr}} install-shim --dir {{dir}} >/dev/null
+`,
+		completion: func(root *cobra.Command, buf *bytes.Buffer) error {
+			return root.GenBashCompletionV2(buf, true)
+		},
+		kubectl: `source <(command {{kuberlr}} ` + kubectlCompletionCmd + ` bash 2>/dev/null)
+`,
+		prompt: `__kuberlr_prompt() {
+  local out
+  out="$(command {{kuberlr}} ` + kubectlVersionHookCmd + ` "${__kuberlr_key-}" 2>/dev/null)" || return
+  if [ -n "$out" ]; then
+    __kuberlr_key="${out%%$'\t'*}"
+    export KUBERLR_KUBECTL="${out#*$'\t'}"
+  fi
+}
+case ";${PROMPT_COMMAND-};" in
+  *";__kuberlr_prompt;"*) ;;
+  *) PROMPT_COMMAND="__kuberlr_prompt${PROMPT_COMMAND:+;$PROMPT_COMMAND}" ;;
+esac
+`,
+	},
+	"zsh": {
+		quote: posixQuote,
+		function: `kubectl() {
+  command {{kuberlr}} kubectl "$@"
+}
+`,
+		path: `case ":$PATH:" in
+  *:{{dir}}:*) ;;
+  *) export PATH={{dir}}":$PATH" ;;
+esac
+[ -e {{kubectl}} ] || command {{kuberlr}} install-shim --dir {{dir}} >/dev/null
+`,
+		completion: func(root *cobra.Command, buf *bytes.Buffer) error {
+			buf.WriteString("(( $+functions[compdef] )) || { autoload -Uz compinit && compinit }\n")
+			return root.GenZshCompletion(buf)
+		},
+		kubectl: `source <(command {{kuberlr}} ` + kubectlCompletionCmd + ` zsh 2>/dev/null)
+`,
+		prompt: `__kuberlr_prompt() {
+  local out
+  out="$(command {{kuberlr}} ` + kubectlVersionHookCmd + ` "${__kuberlr_key-}" 2>/dev/null)" || return
+  if [[ -n "$out" ]]; then
+    __kuberlr_key="${out%%$'\t'*}"
+    export KUBERLR_KUBECTL="${out#*$'\t'}"
+  fi
+}
+autoload -Uz add-zsh-hook
+add-zsh-hook precmd __kuberlr_prompt
+`,
+	},
+	"fish": {
+		quote: fishQuote,
+		function: `function kubectl
+    command {{kuberlr}} kubectl $argv
+end
+`,
+		path: `contains -- {{dir}} $PATH; or set -gx PATH {{dir}} $PATH
+test -e {{kubectl}}; or command {{kuberlr}} install-shim --dir {{dir}} >/dev/null
+`,
+		completion: func(root *cobra.Command, buf *bytes.Buffer) error {
+			return root.GenFishCompletion(buf, true)
+		},
+		kubectl: `command {{kuberlr}} ` + kubectlCompletionCmd + ` fish 2>/dev/null | source
+`,
+		prompt: `function __kuberlr_prompt --on-event fish_prompt
+    set -l out (command {{kuberlr}} ` + kubectlVersionHookCmd + ` "$__kuberlr_key" 2>/dev/null); or return
+    if test -n "$out"
+        set -g __kuberlr_key (string split -m 1 \t -- $out)[1]
+        set -gx KUBERLR_KUBECTL (string split -m 1 \t -- $out)[2]
+    end
+end
+`,
+	},
+	"powershell": {
+		quote: powershellQuote,
+		function: `function global:kubectl {
+    & {{kuberlr}} kubectl @args
+}
+`,
+		path: `if (-not (($env:PATH -split [IO.Path]::PathSeparator) -contains {{dir}})) {
+    $env:PATH = {{dir}} + [IO.Path]::PathSeparator + $env:PATH
+}
+if (-not (Test-Path {{kubectl}})) {
+    & {{kuberlr}} install-shim --dir {{dir}} | Out-Null
+}
+`,
+		completion: func(root *cobra.Command, buf *bytes.Buffer) error {
+			return root.GenPowerShellCompletionWithDesc(buf)
+		},
+		kubectl: `& {{kuberlr}} ` + kubectlCompletionCmd + ` powershell 2>$null | Out-String | Invoke-Expression
+`,
+		prompt: `function global:__kuberlr_prompt {
+    $out = & {{kuberlr}} ` + kubectlVersionHookCmd + ` "$global:__kuberlr_key" 2>$null
+    if ($LASTEXITCODE -eq 0 -and $out) {
+        $parts = "$out" -split "` + "`" + `t", 2
+        $global:__kuberlr_key = $parts[0]
+        $env:KUBERLR_KUBECTL = $parts[1]
+    }
+}
+if (-not $global:__kuberlr_original_prompt) {
+    $global:__kuberlr_original_prompt = $function:prompt
+    function global:prompt {
+        __kuberlr_prompt
+        & $global:__kuberlr_original_prompt
+    }
+}
+`,
+	},
+}
+
+// NewInitCmd creates a new `kuberlr init` cobra command.
+func NewInitCmd() *cobra.Command {
+	var mode string
+	var exportVersion bool
+	shells := supportedShells()
+
+	cmd := &cobra.Command{
+		Use:   "init <" + strings.Join(shells, "|") + ">",
+		Short: "Print the script integrating kuberlr with the shell",
+		Long: `Print the script integrating kuberlr with the shell.
+
+The script routes kubectl through kuberlr, either with a kubectl shell function
+or, with --mode path, by putting a directory holding the kubectl shim at the
+beginning of $PATH. The latter works also for the programs started by the
+shell.
+
+The completion of both kuberlr and kubectl is enabled. The completion script
+of kubectl is generated without contacting the API server nor downloading
+anything, by the kubectl matching the last known version of the API server of
+the current context, or by the most recent kubectl available.
+
+With --export-version, the KUBERLR_KUBECTL environment variable is set to the
+version of the kubectl in use whenever the directory or the kubeconfig context
+changes. It can be shown by the prompt of the shell. Like the completion, it
+never contacts the API server: the last known version of the API server is
+used.`,
+		Example: `
+  Add this line to ~/.bashrc:
+  eval "$(kuberlr init bash)"
+
+  Add this line to ~/.zshrc, after the initialization of the completion:
+  eval "$(kuberlr init zsh --export-version)"
+
+  Add this line to ~/.config/fish/config.fish:
+  kuberlr init fish | source
+
+  Add this line to the PowerShell profile:
+  kuberlr init powershell | Out-String | Invoke-Expression`,
+		Args:         cobra.ExactArgs(1),
+		ValidArgs:    shells,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			script, err := shellInitScript(cmd.Root(), args[0], mode, exportVersion)
+			if err != nil {
+				return err
+			}
+			_, err = os.Stdout.WriteString(script)
+			return err
+		},
+	}
+
+	cmd.Flags().StringVar(&mode, "mode", initModeFunction,
+		fmt.Sprintf("how kubectl is routed through kuberlr: %q or %q", initModeFunction, initModePath))
+	cmd.Flags().BoolVar(&exportVersion, "export-version", false,
+		"keep the KUBERLR_KUBECTL environment variable set to the version of the kubectl in use")
+
+	return cmd
+}
+
+// supportedShells returns the sorted names of the shells kuberlr can be
+// integrated with.
+func supportedShells() []string {
+	shells := make([]string, 0, len(shellInits))
+	for shell := range shellInits {
+		shells = append(shells, shell)
+	}
+	sort.Strings(shells)
+
+	return shells
+}
+
+// shellInitScript returns the script integrating kuberlr with the given
+// shell.
+func shellInitScript(root *cobra.Command, shell, mode string, exportVersion bool) (string, error) {
+	snippets, found := shellInits[shell]
+	if !found {
+		return "", fmt.Errorf("unsupported shell %q, valid values are: %s", shell, strings.Join(supportedShells(), ", "))
+	}
+
+	selfPath := executablePath()
+	if selfPath == "" {
+		return "", errors.New("cannot find the path of the kuberlr binary")
+	}
+	dir := filepath.Join(common.KuberlrDir(), "bin")
+	replacer := strings.NewReplacer(
+		"{{kuberlr}}", snippets.quote(selfPath),
+		"{{dir}}", snippets.quote(dir),
+		"{{kubectl}}", snippets.quote(filepath.Join(dir, "kubectl"+osexec.Ext)),
+	)
+
+	var buf bytes.Buffer
+	switch mode {
+	case initModeFunction:
+		buf.WriteString(replacer.Replace(snippets.function))
+	case initModePath:
+		buf.WriteString(replacer.Replace(snippets.path))
+	default:
+		return "", fmt.Errorf("invalid mode %q, must be either %q or %q", mode, initModeFunction, initModePath)
+	}
+
+	if err := snippets.completion(root, &buf); err != nil {
+		return "", fmt.Errorf("generate the completion of kuberlr: %w", err)
+	}
+	buf.WriteString(replacer.Replace(snippets.kubectl))
+
+	if exportVersion {
+		buf.WriteString(replacer.Replace(snippets.prompt))
+	}
+
+	return buf.String(), nil
+}
+
+// NewKubectlVersionHookCmd creates the hidden command used by the prompt
+// hook to find the version of the kubectl in use. Like the completion of
+// kubectl, the kubectl binary is chosen by offlineKubectl.
+func NewKubectlVersionHookCmd() *cobra.Command {
+	//nolint: forbidigo // it's fine to print to stdout
+	return &cobra.Command{
+		Use:    kubectlVersionHookCmd + " [previous key]",
+		Short:  "Print the version of the kubectl in use, when it could have changed",
+		Hidden: true,
+		Args:   cobra.MaximumNArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			key, err := kubectlVersionHookKey()
+			if err != nil {
+				return err
+			}
+			if len(args) == 1 && args[0] == key {
+				// nothing changed since the last invocation
+				return nil
+			}
+
+			// the prompt is blocked until the hook is over, hence the
+			// kubectl is chosen without contacting the network
+			version := ""
+			v, err := config.NewCfg().Load()
+			if err != nil {
+				return fmt.Errorf("load config: %w", err)
+			}
+			if kubectlBin, offlineErr := offlineKubectl(v); offlineErr == nil {
+				kubectlFinder := finder.NewKubectlFinder("", config.SystemPaths(v))
+				if kubectlVersion, found := kubectlFinder.KubectlVersion(kubectlBin); found {
+					version = kubectlVersion.String()
+				}
+			}
+			fmt.Printf("%s\t%s\n", key, version)
+			return nil
+		},
+	}
+}
+
+// NewKubectlCompletionCmd creates the hidden command used by the script of
+// `kuberlr init` to load the completion of kubectl. It runs on every shell
+// start, hence the kubectl binary is chosen by offlineKubectl.
+func NewKubectlCompletionCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:    kubectlCompletionCmd + " <shell>",
+		Short:  "Print the completion script of kubectl, without contacting the network",
+		Hidden: true,
+		Args:   cobra.ExactArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			v, err := config.NewCfg().Load()
+			if err != nil {
+				return fmt.Errorf("load config: %w", err)
+			}
+			kubectlBin, err := offlineKubectl(v)
+			if err != nil {
+				return err
+			}
+
+			completion := exec.Command(kubectlBin, "completion", args[0])
+			completion.Stdout = os.Stdout
+			completion.Stderr = os.Stderr
+			return completion.Run()
+		},
+	}
+}
+
+// offlineKubectl returns the kubectl binary to use with the current context
+// without contacting the API server nor the mirror. The version of the API
+// server is taken from the server version cache; when unknown, or when no
+// compatible kubectl is available, the most recent kubectl is used.
+func offlineKubectl(v *viper.Viper) (string, error) {
+	settings, err := loadKubectlSettings(v, nil)
+	if err != nil {
+		return "", fmt.Errorf("load kubectl settings: %w", err)
+	}
+	kubectlFinder := finder.NewKubectlFinder("", config.SystemPaths(v))
+	versioner := settings.newVersioner(kubectlFinder)
+
+	if settings.VersionFile != nil && settings.VersionFile.Range != nil {
+		return versioner.Execute(versioner.PlanInRange(settings.VersionFile.Range, false, true))
+	}
+
+	var version semver.Version
+	found := false
+	if settings.VersionFile != nil {
+		version, found = *settings.VersionFile.Version, true
+	} else {
+		version, found, _ = contextServerVersion(settings)
+	}
+	if !found {
+		bins := kubectlFinder.AllKubectlBinaries(true)
+		if len(bins) == 0 {
+			return "", errors.New("no kubectl binary found")
+		}
+		return bins[0].Path, nil
+	}
+
+	return versioner.Execute(settings.planKubectl(versioner, version, false, true))
+}
+
+// kubectlVersionHookKey returns a key identifying what the choice of the
+// kubectl binary depends on: the kubeconfig context and the version file
+// found starting from the working directory. Nothing is contacted over
+// the network.
+func kubectlVersionHookKey() (string, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	versionFile, _, err := finder.FindVersionFile(cwd)
+	if err != nil {
+		return "", err
+	}
+	contextInfo, _ := kubehelper.CurrentContext(nil)
+
+	v, err := config.NewCfg().Load()
+	if err != nil {
+		return "", err
+	}
+	rules, _ := config.LoadContextRules(v)
+
+	hash := sha256.New()
+	for _, item := range []string{
+		contextInfo.Name, contextInfo.Server,
+		versionFile.Path, versionFile.Constraint,
+		fmt.Sprint(rules),
+	} {
+		hash.Write([]byte(item + "\x00"))
+	}
+
+	return hex.EncodeToString(hash.Sum(nil))[:hookKeyLen], nil
+}
+
+// posixQuote quotes the given string for bash and zsh.
+func posixQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// fishQuote quotes the given string for fish.
+func fishQuote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(s) + "'"
+}
+
+// powershellQuote quotes the given string for PowerShell.
+func powershellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
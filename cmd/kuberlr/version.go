@@ -9,14 +9,58 @@ import (
 	"github.com/flavio/kuberlr/pkg/kuberlr"
 )
 
+// versionInfo is the machine-readable output of `kuberlr version`.
+type versionInfo struct {
+	kuberlr.KVersion
+	// ServerVersion is the version of the API server of the current context
+	ServerVersion      string `json:"serverVersion,omitempty"`
+	ServerVersionError string `json:"serverVersionError,omitempty"`
+	// Kubectl is the kubectl binary kuberlr would use right now, it's nil
+	// when it cannot be found
+	Kubectl      *whichTraceDecision `json:"kubectl,omitempty"`
+	KubectlError string              `json:"kubectlError,omitempty"`
+}
+
 // NewVersionCmd creates a new `kuberlr version` cobra command.
 func NewVersionCmd() *cobra.Command {
-	return &cobra.Command{
+	var output string
+
+	cmd := &cobra.Command{
 		Use:   "version",
 		Short: "Print version information",
-		Run: func(_ *cobra.Command, _ []string) {
-			//nolint: forbidigo // it's fine to print to stdout
-			fmt.Printf("%s\n", kuberlr.CurrentVersion().String())
+		Long: `Print version information.
+
+The JSON and YAML outputs include also the version of the API server of the
+current context, and the kubectl binary kuberlr would use right now.`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			if err := validateOutput(output); err != nil {
+				return err
+			}
+			if output == "" {
+				//nolint: forbidigo // it's fine to print to stdout
+				fmt.Printf("%s\n", kuberlr.CurrentVersion().String())
+				return nil
+			}
+
+			info := versionInfo{KVersion: kuberlr.CurrentVersion()}
+			// the version of kuberlr is printed even when the kubectl to
+			// use cannot be found, like with a malformed .kubectl-version
+			trace, err := resolveWhich(nil)
+			if err != nil {
+				info.KubectlError = err.Error()
+				return printStructured(output, info)
+			}
+			info.ServerVersion = trace.ServerVersion
+			info.ServerVersionError = trace.ServerVersionError
+			info.Kubectl = &trace.Decision
+
+			return printStructured(output, info)
 		},
 	}
+
+	cmd.Flags().StringVarP(&output, "output", "o", "", "output format, valid values are: json, yaml")
+
+	return cmd
 }
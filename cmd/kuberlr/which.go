@@ -0,0 +1,317 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/blang/semver/v4"
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/jedib0t/go-pretty/v6/text"
+	"github.com/spf13/cobra"
+
+	"github.com/flavio/kuberlr/internal/config"
+	"github.com/flavio/kuberlr/internal/finder"
+	"github.com/flavio/kuberlr/internal/kubehelper"
+)
+
+// whichTrace describes how kuberlr picks the kubectl binary.
+type whichTrace struct {
+	KubectlArgs        []string           `json:"kubectlArgs"`
+	Kubeconfig         []string           `json:"kubeconfig"`
+	Context            string             `json:"context"`
+	Cluster            string             `json:"cluster"`
+	Server             string             `json:"server"`
+	KubeconfigError    string             `json:"kubeconfigError,omitempty"`
+	Rule               string             `json:"rule,omitempty"`
+	VersionFile        string             `json:"versionFile,omitempty"`
+	VersionSource      string             `json:"versionSource"`
+	ServerVersion      string             `json:"serverVersion,omitempty"`
+	ServerVersionError string             `json:"serverVersionError,omitempty"`
+	RequestedVersion   string             `json:"requestedVersion,omitempty"`
+	RequestedRange     string             `json:"requestedRange,omitempty"`
+	SkewPolicy         string             `json:"skewPolicy,omitempty"`
+	CompatibleRange    string             `json:"compatibleRange,omitempty"`
+	Ranking            []string           `json:"ranking,omitempty"`
+	Candidates         []whichCandidate   `json:"candidates"`
+	Decision           whichTraceDecision `json:"decision"`
+}
+
+// whichCandidate is a kubectl binary taken into account by kuberlr.
+type whichCandidate struct {
+	Path     string  `json:"path"`
+	Version  string  `json:"version"`
+	Origin   string  `json:"origin"`
+	Accepted bool    `json:"accepted"`
+	Selected bool    `json:"selected"`
+	Score    *uint64 `json:"score,omitempty"`
+	Reason   string  `json:"reason"`
+}
+
+// whichTraceDecision is what kuberlr is going to do.
+type whichTraceDecision struct {
+	Action  string `json:"action"`
+	Path    string `json:"path,omitempty"`
+	Version string `json:"version,omitempty"`
+	Reason  string `json:"reason"`
+}
+
+// NewWhichCmd creates a new `kuberlr which` cobra command.
+func NewWhichCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "which [-o json|yaml] [kubectl args...]",
+		Short: "Explain which kubectl binary would be used and why",
+		Long: `Explain which kubectl binary would be used and why, without running it.
+
+The kubectl arguments are used to find the kubeconfig context and the API
+server, like kubectl would do. The flags of kuberlr must come before them.`,
+		Example: `
+  Explain the choice made for the current context:
+  $ kuberlr which
+
+  Explain the choice made for another context, printing JSON:
+  $ kuberlr which -o json --context prod get pods`,
+		DisableFlagParsing: true,
+		ValidArgsFunction:  completeWhichArgs,
+		SilenceUsage:       true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			output, kubectlArgs, help, err := splitWhichArgs(args)
+			if err != nil {
+				return err
+			}
+			if help {
+				return cmd.Help()
+			}
+
+			trace, err := resolveWhich(kubectlArgs)
+			if err != nil {
+				return err
+			}
+
+			if output != "" {
+				return printStructured(output, trace)
+			}
+			printWhichTrace(trace)
+			return nil
+		},
+	}
+}
+
+// splitWhichArgs separates the flags of `kuberlr which` from the kubectl
+// arguments. The flags of kuberlr are only looked for at the beginning,
+// the kubectl arguments start with the first unknown argument or after `--`.
+func splitWhichArgs(args []string) (string, []string, bool, error) {
+	output := ""
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--":
+			return output, args[i+1:], false, validateOutput(output)
+		case arg == "-h" || arg == "--help":
+			return output, nil, true, nil
+		case arg == "-o" || arg == "--output":
+			if i+1 >= len(args) {
+				return "", nil, false, fmt.Errorf("flag %s needs an argument", arg)
+			}
+			i++
+			output = args[i]
+		case strings.HasPrefix(arg, "--output="):
+			output = strings.TrimPrefix(arg, "--output=")
+		case strings.HasPrefix(arg, "-o"):
+			output = strings.TrimPrefix(strings.TrimPrefix(arg, "-o"), "=")
+		default:
+			return output, args[i:], false, validateOutput(output)
+		}
+	}
+
+	return output, []string{}, false, validateOutput(output)
+}
+
+// resolveWhich goes through the same steps of the kubectl wrapper mode,
+// without downloading or running anything.
+func resolveWhich(kubectlArgs []string) (whichTrace, error) {
+	v, err := config.NewCfg().Load()
+	if err != nil {
+		return whichTrace{}, fmt.Errorf("load config: %w", err)
+	}
+
+	settings, err := loadKubectlSettings(v, kubectlArgs)
+	if err != nil {
+		return whichTrace{}, fmt.Errorf("load kubectl settings: %w", err)
+	}
+
+	trace := whichTrace{
+		KubectlArgs: kubectlArgs,
+		Candidates:  []whichCandidate{},
+	}
+
+	contextInfo, err := kubehelper.CurrentContext(kubectlArgs)
+	if err != nil {
+		trace.KubeconfigError = err.Error()
+	}
+	trace.Kubeconfig = contextInfo.Kubeconfig
+	trace.Context = contextInfo.Name
+	trace.Cluster = contextInfo.Cluster
+	trace.Server = contextInfo.Server
+	if settings.Rule != nil {
+		trace.Rule = settings.Rule.String()
+	}
+	if settings.VersionFile != nil {
+		trace.VersionFile = settings.VersionFile.Path
+	}
+
+	kubectlFinder := finder.NewKubectlFinder("", config.SystemPaths(v))
+	versioner := settings.newVersioner(kubectlFinder)
+
+	if settings.VersionFile != nil && settings.VersionFile.Range != nil {
+		trace.VersionSource = versionSourceVersionFile
+		trace.RequestedRange = settings.VersionFile.Constraint
+		tracePlan(&trace, versioner.PlanInRange(
+			settings.VersionFile.Range, settings.AllowDownload, settings.UseLatestIfNoCompatible))
+		return trace, nil
+	}
+
+	requested, err := settings.kubectlVersionToUse(versioner)
+	trace.VersionSource = requested.Source
+	if requested.ServerErr != nil {
+		trace.ServerVersionError = requested.ServerErr.Error()
+	}
+	if err != nil {
+		trace.Decision = whichTraceDecision{
+			Action: finder.ActionFail,
+			Reason: fmt.Sprintf("cannot find the kubectl version to use: %v", err),
+		}
+		return trace, nil
+	}
+	if requested.Source == versionSourceServer {
+		trace.ServerVersion = requested.Version.String()
+	}
+	trace.RequestedVersion = requested.Version.String()
+	traceCompatible(&trace, settings, versioner, requested.Version)
+
+	return trace, nil
+}
+
+// traceCompatible renders the plan made by the versioner to find a kubectl
+// compatible with the given version, or with exactly the pinned version.
+func traceCompatible(trace *whichTrace, settings kubectlSettings, versioner *finder.Versioner, version semver.Version) {
+	if settings.pinnedExactly() {
+		// the pinned version is used as it is, no skew policy nor ranking
+		tracePlan(trace, settings.planKubectl(versioner, version, settings.AllowDownload, settings.UseLatestIfNoCompatible))
+		return
+	}
+
+	policy := versioner.SkewPolicy()
+	trace.SkewPolicy = policy.String()
+	trace.CompatibleRange = policy.RangeRule(version)
+	trace.Ranking = versioner.RankingPreferences()
+
+	tracePlan(trace, settings.planKubectl(versioner, version, settings.AllowDownload, settings.UseLatestIfNoCompatible))
+}
+
+// tracePlan fills the candidates and the decision of the trace with the
+// ones of the plan.
+func tracePlan(trace *whichTrace, plan finder.Plan) {
+	for _, c := range plan.Candidates {
+		trace.Candidates = append(trace.Candidates, whichCandidate{
+			Path:     c.Path,
+			Version:  c.Version.String(),
+			Origin:   c.Origin,
+			Accepted: c.Accepted,
+			Selected: c.Selected,
+			Score:    c.Score,
+			Reason:   c.Reason,
+		})
+	}
+
+	trace.Decision = whichTraceDecision{
+		Action: plan.Action,
+		Path:   plan.Binary.Path,
+		Reason: plan.Reason,
+	}
+	if plan.Action != finder.ActionFail && plan.Binary.Path != "" {
+		trace.Decision.Version = plan.Binary.Version.String()
+	}
+	if plan.Fallback != nil {
+		trace.Decision.Reason += fmt.Sprintf("; if the download fails %s is used", plan.Fallback.Path)
+	}
+}
+
+//nolint:forbidigo // it's fine to print to stdout
+func printWhichTrace(trace whichTrace) {
+	header := func(title string) {
+		fmt.Printf("\n%s\n", text.FgGreen.Sprint(title))
+	}
+	orNone := func(value string) string {
+		if value == "" {
+			return "-"
+		}
+		return value
+	}
+
+	header("kubeconfig")
+	fmt.Printf("files:   %s\n", orNone(strings.Join(trace.Kubeconfig, string(os.PathListSeparator))))
+	fmt.Printf("context: %s\n", orNone(trace.Context))
+	fmt.Printf("cluster: %s\n", orNone(trace.Cluster))
+	fmt.Printf("server:  %s\n", orNone(trace.Server))
+	if trace.KubeconfigError != "" {
+		fmt.Printf("error:   %s\n", trace.KubeconfigError)
+	}
+	if trace.Rule != "" {
+		fmt.Printf("rule:    %s\n", trace.Rule)
+	}
+
+	header("version")
+	switch trace.VersionSource {
+	case versionSourceVersionFile:
+		fmt.Printf("pinned by %s\n", trace.VersionFile)
+	case versionSourceContextRule:
+		fmt.Printf("pinned by rule %s\n", trace.Rule)
+	case versionSourceServer:
+		fmt.Printf("API server version: %s\n", trace.ServerVersion)
+	case versionSourceFallback:
+		fmt.Printf("API server version: %s\n", text.FgRed.Sprint(trace.ServerVersionError))
+		fmt.Printf("falling back to the most recent kubectl available\n")
+	}
+	if trace.RequestedRange != "" {
+		fmt.Printf("requested range: %s\n", trace.RequestedRange)
+	}
+	if trace.RequestedVersion != "" {
+		fmt.Printf("requested version: %s\n", trace.RequestedVersion)
+	}
+	if trace.CompatibleRange != "" {
+		fmt.Printf("skew policy: %s, compatible range: %s\n", trace.SkewPolicy, trace.CompatibleRange)
+		fmt.Printf("ranking: %v\n", trace.Ranking)
+	}
+
+	header("candidates")
+	if len(trace.Candidates) == 0 {
+		fmt.Println("No kubectl binary found")
+	} else {
+		tableWriter := table.NewWriter()
+		tableWriter.SetOutputMirror(os.Stdout)
+		tableWriter.AppendHeader(table.Row{"", "Version", "Binary", "Reason"})
+		for _, c := range trace.Candidates {
+			mark := ""
+			switch {
+			case c.Selected:
+				mark = "*"
+			case !c.Accepted:
+				mark = "x"
+			}
+			tableWriter.AppendRow(table.Row{mark, c.Version, c.Path, c.Reason})
+		}
+		tableWriter.Render()
+	}
+
+	header("decision")
+	fmt.Printf("%s", trace.Decision.Action)
+	if trace.Decision.Version != "" {
+		fmt.Printf(" kubectl %s", trace.Decision.Version)
+	}
+	if trace.Decision.Path != "" {
+		fmt.Printf(" (%s)", trace.Decision.Path)
+	}
+	fmt.Printf(": %s\n", trace.Decision.Reason)
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitWhichArgs(t *testing.T) {
+	tests := []struct {
+		name        string
+		args        []string
+		output      string
+		kubectlArgs []string
+		help        bool
+		expectsErr  bool
+	}{
+		{
+			name:        "no arguments",
+			args:        []string{},
+			kubectlArgs: []string{},
+		},
+		{
+			name:        "only kubectl arguments",
+			args:        []string{"--context", "prod", "get", "pods"},
+			kubectlArgs: []string{"--context", "prod", "get", "pods"},
+		},
+		{
+			name:        "output flag",
+			args:        []string{"-o", "json", "--context", "prod"},
+			output:      "json",
+			kubectlArgs: []string{"--context", "prod"},
+		},
+		{
+			name:        "long output flag with value",
+			args:        []string{"--output=yaml", "get", "pods"},
+			output:      "yaml",
+			kubectlArgs: []string{"get", "pods"},
+		},
+		{
+			name:        "short output flag with value",
+			args:        []string{"-ojson"},
+			output:      "json",
+			kubectlArgs: []string{},
+		},
+		{
+			name:        "double dash",
+			args:        []string{"-o", "json", "--", "-o", "wide"},
+			output:      "json",
+			kubectlArgs: []string{"-o", "wide"},
+		},
+		{
+			name:        "output flag of kubectl",
+			args:        []string{"get", "pods", "-o", "wide"},
+			kubectlArgs: []string{"get", "pods", "-o", "wide"},
+		},
+		{
+			name:   "help",
+			args:   []string{"-o", "json", "--help"},
+			output: "json",
+			help:   true,
+		},
+		{
+			name:       "missing output value",
+			args:       []string{"-o"},
+			expectsErr: true,
+		},
+		{
+			name:       "invalid output",
+			args:       []string{"-o", "wide", "get", "pods"},
+			expectsErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output, kubectlArgs, help, err := splitWhichArgs(tt.args)
+			if tt.expectsErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.output, output)
+			assert.Equal(t, tt.kubectlArgs, kubectlArgs)
+			assert.Equal(t, tt.help, help)
+		})
+	}
+}
@@ -2,6 +2,7 @@ package common
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -27,14 +28,139 @@ func HomeDir() string {
 	return os.Getenv(HomeDirEnvKey())
 }
 
+// KuberlrDir returns the path to the directory where kuberlr keeps
+// its per-user data.
+func KuberlrDir() string {
+	return filepath.Join(HomeDir(), ".kuberlr")
+}
+
 // LocalDownloadDir return the path to where kuberlr saves
 // the kubectl binaries downloaded from kubernetes' upstream mirror.
 func LocalDownloadDir() string {
 	platform := fmt.Sprintf("%s-%s", runtime.GOOS, runtime.GOARCH)
 
 	return filepath.Join(
-		HomeDir(),
-		".kuberlr",
+		KuberlrDir(),
 		platform,
 	)
 }
+
+// WriteFileAtomically writes data to the file at the given path, creating
+// its parent directories when needed. The data is written to a temporary
+// file that is then renamed, hence readers never see a partially written
+// file.
+func WriteFileAtomically(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return err
+	}
+
+	tmpFile, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpFile.Name())
+
+	if _, err = tmpFile.Write(data); err != nil {
+		tmpFile.Close()
+		return err
+	}
+	if err = tmpFile.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmpFile.Name(), path)
+}
+
+// CheckWritableDir ensures files can be created inside of the given
+// directory. The directory is created with the given permissions when
+// it doesn't exist.
+func CheckWritableDir(dir string, perm os.FileMode) error {
+	if err := os.MkdirAll(dir, perm); err != nil {
+		return err
+	}
+
+	return CheckWritable(dir)
+}
+
+// CheckWritable ensures files can be created inside of the given existing
+// directory. Nothing is left behind.
+func CheckWritable(dir string) error {
+	probe, err := os.CreateTemp(dir, ".kuberlr-")
+	if err != nil {
+		return err
+	}
+	probe.Close()
+
+	return os.Remove(probe.Name())
+}
+
+// NearestExistingDir returns the given directory when it exists, otherwise
+// its closest ancestor that exists.
+func NearestExistingDir(dir string) (string, error) {
+	dir = filepath.Clean(dir)
+	for {
+		info, err := os.Stat(dir)
+		switch {
+		case err == nil && info.IsDir():
+			return dir, nil
+		case err == nil:
+			return "", fmt.Errorf("%s is not a directory", dir)
+		case !os.IsNotExist(err):
+			return "", err
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", fmt.Errorf("no ancestor of %s exists", dir)
+		}
+		dir = parent
+	}
+}
+
+// CopyFile copies the file at src to dst, preserving its permissions. The
+// copy is written to a temporary file that is then renamed.
+func CopyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+
+	tmpFile, err := os.CreateTemp(filepath.Dir(dst), filepath.Base(dst)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpFile.Name())
+
+	if _, err = io.Copy(tmpFile, in); err != nil {
+		tmpFile.Close()
+		return err
+	}
+	if err = tmpFile.Close(); err != nil {
+		return err
+	}
+	if err = os.Chmod(tmpFile.Name(), info.Mode().Perm()); err != nil {
+		return err
+	}
+
+	return os.Rename(tmpFile.Name(), dst)
+}
+
+// MoveFile moves the file at src to dst. The file is copied, and then
+// removed, when the two paths are on different filesystems.
+func MoveFile(src, dst string) error {
+	err := os.Rename(src, dst)
+	if err == nil || os.IsPermission(err) || os.IsNotExist(err) {
+		return err
+	}
+
+	if err = CopyFile(src, dst); err != nil {
+		return err
+	}
+	return os.Remove(src)
+}
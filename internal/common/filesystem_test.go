@@ -0,0 +1,105 @@
+package common_test
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/flavio/kuberlr/internal/common"
+)
+
+func TestCheckWritableDir(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "bin")
+
+	if err := common.CheckWritableDir(dir, 0o755); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("The directory has not been created: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("The probe file has not been removed: %v", entries)
+	}
+
+	if runtime.GOOS == "windows" || os.Geteuid() == 0 {
+		t.Skip("permissions are not enforced")
+	}
+	if err = os.Chmod(dir, 0o555); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chmod(dir, 0o755) //nolint: errcheck // best effort cleanup
+	if err = common.CheckWritableDir(dir, 0o755); !os.IsPermission(err) {
+		t.Errorf("Expected a permission error, got %v", err)
+	}
+}
+
+func TestMoveFile(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "kubectl")
+	dst := filepath.Join(dir, "kubectl1.28")
+	if err := os.WriteFile(src, []byte("kubectl"), 0o755); err != nil { //nolint: gosec // the file must be executable
+		t.Fatal(err)
+	}
+
+	if err := common.MoveFile(src, dst); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := os.Stat(src); !os.IsNotExist(err) {
+		t.Errorf("The source file still exists: %v", err)
+	}
+	info, err := os.Stat(dst)
+	if err != nil {
+		t.Fatalf("The file has not been moved: %v", err)
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm() != 0o755 {
+		t.Errorf("Expected mode 0755, got %v", info.Mode().Perm())
+	}
+}
+
+func TestCopyFile(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "kuberlr")
+	dst := filepath.Join(dir, "kubectl")
+	if err := os.WriteFile(src, []byte("kuberlr"), 0o750); err != nil { //nolint: gosec // the file must be executable
+		t.Fatal(err)
+	}
+
+	if err := common.CopyFile(src, dst); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	data, err := os.ReadFile(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "kuberlr" {
+		t.Errorf("Unexpected content: %q", data)
+	}
+	if _, err = os.Stat(src); err != nil {
+		t.Errorf("The source file is gone: %v", err)
+	}
+}
+
+func TestNearestExistingDir(t *testing.T) {
+	root := t.TempDir()
+
+	dir, err := common.NearestExistingDir(filepath.Join(root, "a", "b"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if dir != root {
+		t.Errorf("Expected %s, got %s", root, dir)
+	}
+	if _, err = os.Stat(filepath.Join(root, "a")); !os.IsNotExist(err) {
+		t.Errorf("The directory has been created: %v", err)
+	}
+
+	file := filepath.Join(root, "file")
+	if err = os.WriteFile(file, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = common.NearestExistingDir(filepath.Join(file, "a")); err == nil {
+		t.Error("Expected an error for an ancestor that is not a directory")
+	}
+}
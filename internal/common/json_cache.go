@@ -0,0 +1,120 @@
+package common
+
+import (
+	"encoding/json"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// CacheEntry is an entry of a JSONCache.
+type CacheEntry interface {
+	// CachedAt returns when the entry has been stored
+	CachedAt() time.Time
+}
+
+// JSONCache keeps on disk, as a JSON object, entries identified by a key.
+// Entries older than the TTL are expired, callers decide whether they can
+// still be used. The cache can be used by multiple goroutines at the same
+// time.
+type JSONCache[E CacheEntry] struct {
+	path    string
+	ttl     time.Duration
+	mu      sync.Mutex
+	entries map[string]E
+}
+
+// LoadJSONCache reads the cache stored at the given path. A missing file
+// results in an empty cache. Entries older than ttl are considered expired.
+// A usable, empty, cache is returned together with the error when the file
+// cannot be read.
+func LoadJSONCache[E CacheEntry](path string, ttl time.Duration) (*JSONCache[E], error) {
+	cache := &JSONCache[E]{
+		path:    path,
+		ttl:     ttl,
+		entries: map[string]E{},
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return cache, nil
+		}
+		return cache, err
+	}
+	if err = json.Unmarshal(data, &cache.entries); err != nil {
+		cache.entries = map[string]E{}
+		return cache, err
+	}
+
+	return cache, nil
+}
+
+// TTL returns how long the entries of the cache are considered fresh.
+func (c *JSONCache[E]) TTL() time.Duration {
+	return c.ttl
+}
+
+// Get returns the entry stored with the given key. The first boolean tells
+// whether the entry has been found, the second one whether it's still
+// fresh.
+func (c *JSONCache[E]) Get(key string) (E, bool, bool) {
+	c.mu.Lock()
+	entry, found := c.entries[key]
+	c.mu.Unlock()
+	if !found {
+		return entry, false, false
+	}
+
+	return entry, true, time.Since(entry.CachedAt()) < c.ttl
+}
+
+// Put stores the entry with the given key and writes the cache to disk.
+func (c *JSONCache[E]) Put(key string, entry E) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[key] = entry
+
+	data, err := json.Marshal(c.entries)
+	if err != nil {
+		return err
+	}
+
+	return WriteFileAtomically(c.path, data)
+}
+
+// Entries returns all the entries of the cache, sorted by key.
+func (c *JSONCache[E]) Entries() []E {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	keys := make([]string, 0, len(c.entries))
+	for key := range c.entries {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	entries := make([]E, 0, len(keys))
+	for _, key := range keys {
+		entries = append(entries, c.entries[key])
+	}
+
+	return entries
+}
+
+// Clear removes all the entries of the cache, including the file on disk.
+func (c *JSONCache[E]) Clear() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries = map[string]E{}
+
+	err := os.Remove(c.path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
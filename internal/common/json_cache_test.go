@@ -0,0 +1,78 @@
+package common_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/flavio/kuberlr/internal/common"
+)
+
+type testCacheEntry struct {
+	Value    string    `json:"value"`
+	StoredAt time.Time `json:"storedAt"`
+}
+
+func (e testCacheEntry) CachedAt() time.Time {
+	return e.StoredAt
+}
+
+func TestJSONCache(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.json")
+
+	cache, err := common.LoadJSONCache[testCacheEntry](path, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, found, _ := cache.Get("b"); found {
+		t.Error("unexpected entry in an empty cache")
+	}
+	if err = cache.Put("b", testCacheEntry{Value: "fresh", StoredAt: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+	if err = cache.Put("a", testCacheEntry{Value: "old", StoredAt: time.Now().Add(-2 * time.Hour)}); err != nil {
+		t.Fatal(err)
+	}
+
+	cache, err = common.LoadJSONCache[testCacheEntry](path, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry, found, fresh := cache.Get("b")
+	if !found || !fresh || entry.Value != "fresh" {
+		t.Errorf("unexpected entry %+v, found %v, fresh %v", entry, found, fresh)
+	}
+	entry, found, fresh = cache.Get("a")
+	if !found || fresh || entry.Value != "old" {
+		t.Errorf("unexpected entry %+v, found %v, fresh %v", entry, found, fresh)
+	}
+	if entries := cache.Entries(); len(entries) != 2 || entries[0].Value != "old" {
+		t.Errorf("unexpected entries %+v", entries)
+	}
+
+	if err = cache.Clear(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("expected the cache file to be removed, got %v", err)
+	}
+	if entries := cache.Entries(); len(entries) != 0 {
+		t.Errorf("unexpected entries %+v", entries)
+	}
+}
+
+func TestLoadJSONCacheCorrupted(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.json")
+	if err := os.WriteFile(path, []byte("{"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cache, err := common.LoadJSONCache[testCacheEntry](path, time.Hour)
+	if err == nil {
+		t.Error("expected an error")
+	}
+	if cache == nil || len(cache.Entries()) != 0 {
+		t.Error("expected a usable, empty, cache")
+	}
+}
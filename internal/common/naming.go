@@ -9,7 +9,8 @@ import (
 )
 
 // KubectlLocalNamingScheme holds the scheme used to name the kubectl binaries
-// downloaded by kuberlr.
+// downloaded by kuberlr. Pre-release versions have their identifiers
+// appended to the name: `kubectl1.31.0-rc.1`.
 const KubectlLocalNamingScheme = "kubectl%d.%d.%d"
 
 // KubectlSystemNamingScheme holds the scheme used to name the kubectl binaries
@@ -19,7 +20,7 @@ const KubectlSystemNamingScheme = "kubectl%d.%d"
 // BuildKubectlNameForLocalBin returns how kuberlr will name the kubectl binary
 // with the specified version when downloading that to the user home.
 func BuildKubectlNameForLocalBin(v semver.Version) string {
-	return fmt.Sprintf(KubectlLocalNamingScheme+osexec.Ext, v.Major, v.Minor, v.Patch)
+	return "kubectl" + ReleaseVersion(v).String() + osexec.Ext
 }
 
 // BuildKubectlNameForSystemBin returns how kuberlr expects system-wide
@@ -27,3 +28,43 @@ func BuildKubectlNameForLocalBin(v semver.Version) string {
 func BuildKubectlNameForSystemBin(version semver.Version) string {
 	return fmt.Sprintf(KubectlSystemNamingScheme+osexec.Ext, version.Major, version.Minor)
 }
+
+// ReleaseVersion returns the given version without its build metadata.
+// This is the version kubernetes releases are published with.
+func ReleaseVersion(v semver.Version) semver.Version {
+	return semver.Version{
+		Major: v.Major,
+		Minor: v.Minor,
+		Patch: v.Patch,
+		Pre:   v.Pre,
+	}
+}
+
+// KubernetesVersion returns the upstream kubernetes version corresponding
+// to the one reported by a cluster or a binary. Build metadata is dropped,
+// like the suffixes added by vendors to the pre-release field
+// (e.g.: `v1.28.4-eks-8cb36c9`, `v1.27.3-gke.100`). Only the upstream
+// pre-release identifiers (alpha, beta and rc) are kept.
+func KubernetesVersion(v semver.Version) semver.Version {
+	release := ReleaseVersion(v)
+	if !IsUpstreamPreRelease(release) {
+		release.Pre = nil
+	}
+
+	return release
+}
+
+// IsUpstreamPreRelease returns true when the given version is an alpha,
+// beta or release candidate of kubernetes.
+func IsUpstreamPreRelease(v semver.Version) bool {
+	if len(v.Pre) == 0 || v.Pre[0].IsNum {
+		return false
+	}
+
+	switch v.Pre[0].VersionStr {
+	case "alpha", "beta", "rc":
+		return true
+	default:
+		return false
+	}
+}
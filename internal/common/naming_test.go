@@ -0,0 +1,46 @@
+package common_test
+
+import (
+	"testing"
+
+	"github.com/blang/semver/v4"
+
+	"github.com/flavio/kuberlr/internal/common"
+	"github.com/flavio/kuberlr/internal/osexec"
+)
+
+func TestBuildKubectlNameForLocalBin(t *testing.T) {
+	tests := map[string]string{
+		"1.28.4":           "kubectl1.28.4",
+		"1.31.0-rc.1":      "kubectl1.31.0-rc.1",
+		"1.31.0-alpha.2":   "kubectl1.31.0-alpha.2",
+		"1.28.4+k3s1":      "kubectl1.28.4",
+		"1.31.0-beta.0+a1": "kubectl1.31.0-beta.0",
+	}
+
+	for version, expected := range tests {
+		actual := common.BuildKubectlNameForLocalBin(semver.MustParse(version))
+		if actual != expected+osexec.Ext {
+			t.Errorf("version %s: expected %q, got %q", version, expected+osexec.Ext, actual)
+		}
+	}
+}
+
+func TestKubernetesVersion(t *testing.T) {
+	tests := map[string]string{
+		"1.28.4":              "1.28.4",
+		"1.28.4+k3s1":         "1.28.4",
+		"1.28.4-eks-8cb36c9":  "1.28.4",
+		"1.27.3-gke.100":      "1.27.3",
+		"1.31.0-rc.1":         "1.31.0-rc.1",
+		"1.31.0-alpha.3+abcd": "1.31.0-alpha.3",
+		"1.31.0-beta.0":       "1.31.0-beta.0",
+	}
+
+	for version, expected := range tests {
+		actual := common.KubernetesVersion(semver.MustParse(version))
+		if actual.String() != expected {
+			t.Errorf("version %s: expected %s, got %s", version, expected, actual)
+		}
+	}
+}
@@ -1,15 +1,24 @@
 package common
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 // NoVersionFoundError error is raised when no kubectl binary
-// has yet been downloaded by kuberlr.
+// has yet been downloaded by kuberlr, or when none of them
+// is inside of the range of versions that was tried.
 type NoVersionFoundError struct {
 	Err error
+	// Range holds the semver range of the versions that was tried
+	Range string
 }
 
 // Error returns a human description of the error.
 func (e *NoVersionFoundError) Error() string {
+	if e.Range != "" {
+		return fmt.Sprintf("No local kubectl binary inside of range %q available", e.Range)
+	}
 	return "No local kubectl binaries available"
 }
 
@@ -0,0 +1,29 @@
+//go:build linux || darwin
+// +build linux darwin
+
+package common
+
+import (
+	"os"
+	"syscall"
+)
+
+// InheritOwnership makes the given file owned by the user and the group
+// owning its parent directory. It does nothing unless kuberlr is running
+// as root, which is the only user allowed to change the ownership of files.
+func InheritOwnership(path, dir string) error {
+	if os.Geteuid() != 0 {
+		return nil
+	}
+
+	info, err := os.Stat(dir)
+	if err != nil {
+		return err
+	}
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return nil
+	}
+
+	return os.Chown(path, int(stat.Uid), int(stat.Gid))
+}
@@ -0,0 +1,10 @@
+//go:build windows
+// +build windows
+
+package common
+
+// InheritOwnership does nothing on windows, where files inherit the
+// permissions of their parent directory.
+func InheritOwnership(_, _ string) error {
+	return nil
+}
@@ -0,0 +1,68 @@
+package common
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// sizeUnits maps the suffixes accepted by ParseSize to their multiplier.
+var sizeUnits = map[string]int64{ //nolint: gochecknoglobals // maps cannot be go constants
+	"":    1,
+	"B":   1,
+	"K":   1000,
+	"KB":  1000,
+	"KIB": 1 << 10,
+	"M":   1000 * 1000,
+	"MB":  1000 * 1000,
+	"MIB": 1 << 20,
+	"G":   1000 * 1000 * 1000,
+	"GB":  1000 * 1000 * 1000,
+	"GIB": 1 << 30,
+	"T":   1000 * 1000 * 1000 * 1000,
+	"TB":  1000 * 1000 * 1000 * 1000,
+	"TIB": 1 << 40,
+}
+
+// ParseSize parses a size like `500MB`, `2GiB` or `1048576`. Units are case
+// insensitive: `KB`, `MB`, `GB` and `TB` are powers of 1000, while `KiB`,
+// `MiB`, `GiB` and `TiB` are powers of 1024. Numbers without unit are bytes.
+func ParseSize(s string) (int64, error) {
+	value := strings.TrimSpace(s)
+	unitStart := strings.IndexFunc(value, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	number, unit := value, ""
+	if unitStart >= 0 {
+		number, unit = value[:unitStart], strings.TrimSpace(value[unitStart:])
+	}
+
+	multiplier, found := sizeUnits[strings.ToUpper(unit)]
+	if !found {
+		return 0, fmt.Errorf("invalid size %q: unknown unit %q", s, unit)
+	}
+
+	n, err := strconv.ParseFloat(number, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+
+	return int64(n * float64(multiplier)), nil
+}
+
+// FormatSize returns a human readable representation of the given number of
+// bytes, using powers of 1024.
+func FormatSize(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %ciB", float64(bytes)/float64(div), "KMGTPE"[exp])
+}
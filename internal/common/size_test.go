@@ -0,0 +1,51 @@
+package common_test
+
+import (
+	"testing"
+
+	"github.com/flavio/kuberlr/internal/common"
+)
+
+func TestParseSize(t *testing.T) {
+	tests := map[string]int64{
+		"1048576": 1048576,
+		"500MB":   500 * 1000 * 1000,
+		"500mb":   500 * 1000 * 1000,
+		"2GiB":    2 << 30,
+		"1.5 GB":  1500 * 1000 * 1000,
+		"10K":     10000,
+		"0":       0,
+	}
+
+	for size, expected := range tests {
+		actual, err := common.ParseSize(size)
+		if err != nil {
+			t.Errorf("size %q: unexpected error %v", size, err)
+			continue
+		}
+		if actual != expected {
+			t.Errorf("size %q: expected %d, got %d", size, expected, actual)
+		}
+	}
+
+	for _, size := range []string{"", "MB", "10 parsecs", "-1GB"} {
+		if _, err := common.ParseSize(size); err == nil {
+			t.Errorf("size %q: expected an error", size)
+		}
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	tests := map[int64]string{
+		512:      "512 B",
+		2048:     "2.0 KiB",
+		52428800: "50.0 MiB",
+		3 << 30:  "3.0 GiB",
+	}
+
+	for bytes, expected := range tests {
+		if actual := common.FormatSize(bytes); actual != expected {
+			t.Errorf("%d bytes: expected %q, got %q", bytes, expected, actual)
+		}
+	}
+}
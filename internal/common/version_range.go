@@ -0,0 +1,163 @@
+package common
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/blang/semver/v4"
+)
+
+var versionInRangeRe = regexp.MustCompile( //nolint: gochecknoglobals // regexps cannot be go constants
+	`(^|[\s<>=!])v?(\d+)(\.(\d+|x))?(\.(\d+|x))?(-[0-9A-Za-z.-]+)?`)
+
+// VersionRange is a range of versions, like `>=1.27 <1.29`. Besides matching
+// versions, it knows the lowest and the highest minor versions it can match:
+// this allows to look for its releases without walking all the minor
+// versions.
+type VersionRange struct {
+	match semver.Range
+	// lowest and highest are the bounds of the minor versions matched by
+	// the range, their patch level is always zero. They are nil when the
+	// range is unbounded on that side.
+	lowest  *semver.Version
+	highest *semver.Version
+}
+
+// ParseVersionRange parses a semver range like `>=1.27 <1.29`. Unlike
+// semver.ParseRange, versions can have the `v` prefix and can omit the
+// minor and patch levels: missing levels are handled as wildcards, hence
+// `1.28` is equivalent to `1.28.x`.
+func ParseVersionRange(s string) (*VersionRange, error) {
+	normalized := versionInRangeRe.ReplaceAllStringFunc(
+		strings.TrimSpace(s),
+		func(match string) string {
+			sub := versionInRangeRe.FindStringSubmatch(match)
+			prefix, major, minor, patch, pre := sub[1], sub[2], sub[4], sub[6], sub[7]
+			if minor == "" {
+				minor = "x"
+			}
+			if patch == "" {
+				patch = "x"
+			}
+			return prefix + major + "." + minor + "." + patch + pre
+		})
+
+	match, err := semver.ParseRange(normalized)
+	if err != nil {
+		return nil, err
+	}
+
+	versionRange := &VersionRange{match: match}
+	versionRange.lowest, versionRange.highest = minorBounds(normalized)
+
+	return versionRange, nil
+}
+
+// Contains returns true when the given version is inside of the range.
+func (r *VersionRange) Contains(v semver.Version) bool {
+	return r.match(v)
+}
+
+// MinorBounds returns the lowest and the highest minor versions, with a
+// zero patch level, the range can match. A nil bound means the range is not
+// bounded on that side. The bounds can be wider than the range, never
+// narrower.
+func (r *VersionRange) MinorBounds() (*semver.Version, *semver.Version) {
+	return r.lowest, r.highest
+}
+
+// minorBounds computes the bounds of the minor versions matched by the given
+// range, already validated by semver.ParseRange. The bounds of the
+// alternatives of the range, separated by `||`, are merged together.
+func minorBounds(normalized string) (*semver.Version, *semver.Version) {
+	var lowest, highest *semver.Version
+
+	for i, alternative := range strings.Split(normalized, "||") {
+		lower, upper := comparatorsMinorBounds(alternative)
+		if i == 0 || lowest != nil && (lower == nil || lower.LT(*lowest)) {
+			lowest = lower
+		}
+		if i == 0 || highest != nil && (upper == nil || upper.GT(*highest)) {
+			highest = upper
+		}
+	}
+
+	return lowest, highest
+}
+
+// comparatorsMinorBounds computes the bounds of the minor versions matched
+// by comparators that must be all satisfied, like `>=1.27.0 <1.29.0`.
+func comparatorsMinorBounds(comparators string) (*semver.Version, *semver.Version) {
+	var lowest, highest *semver.Version
+
+	op := ""
+	for _, field := range strings.Fields(comparators) {
+		// operators can be separated from their version: `>= 1.27.0`
+		if strings.Trim(field, "<>=!") == "" {
+			op = field
+			continue
+		}
+		trimmed := strings.TrimLeft(field, "<>=!")
+		lower, upper := comparatorMinorBounds(op+field[:len(field)-len(trimmed)], trimmed)
+		op = ""
+
+		if lower != nil && (lowest == nil || lower.GT(*lowest)) {
+			lowest = lower
+		}
+		if upper != nil && (highest == nil || upper.LT(*highest)) {
+			highest = upper
+		}
+	}
+
+	return lowest, highest
+}
+
+// comparatorMinorBounds computes the bounds of the minor versions matched by
+// a single comparator, like `>=` and `1.27.x`. Pre-releases are ignored,
+// which can only make the bounds wider.
+func comparatorMinorBounds(op, version string) (*semver.Version, *semver.Version) {
+	parts := strings.SplitN(strings.SplitN(version, "-", 2)[0], ".", 3) //nolint: mnd // major, minor and patch
+	major, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil || len(parts) != 3 {
+		return nil, nil
+	}
+	anyMinor := parts[1] == "x"
+	anyPatch := parts[2] == "x"
+	var minor uint64
+	if !anyMinor {
+		if minor, err = strconv.ParseUint(parts[1], 10, 64); err != nil {
+			return nil, nil
+		}
+	}
+
+	first := &semver.Version{Major: major, Minor: minor}
+	last := &semver.Version{Major: major, Minor: minor}
+	if anyMinor {
+		last.Minor = math.MaxUint64
+	}
+
+	switch op {
+	case "", "=", "==":
+		return first, last
+	case ">=":
+		return first, nil
+	case ">":
+		switch {
+		case anyMinor:
+			return &semver.Version{Major: major + 1}, nil
+		case anyPatch:
+			return &semver.Version{Major: major, Minor: minor + 1}, nil
+		}
+		return first, nil
+	case "<":
+		// `<1.29.0` matches the 1.29 pre-releases
+		return nil, first
+	case "<=":
+		return nil, last
+	}
+
+	// `!=` excludes a single version
+	return nil, nil
+}
@@ -0,0 +1,104 @@
+package common_test
+
+import (
+	"testing"
+
+	"github.com/blang/semver/v4"
+
+	"github.com/flavio/kuberlr/internal/common"
+)
+
+func TestParseVersionRange(t *testing.T) {
+	tests := []struct {
+		rangeStr   string
+		matching   []string
+		unmatching []string
+	}{
+		{
+			rangeStr:   "1.28",
+			matching:   []string{"1.28.0", "1.28.12"},
+			unmatching: []string{"1.27.9", "1.29.0"},
+		},
+		{
+			rangeStr:   ">=1.27 <1.29",
+			matching:   []string{"1.27.0", "1.28.3"},
+			unmatching: []string{"1.26.5", "1.29.0"},
+		},
+		{
+			rangeStr:   ">= v1.27.3 <=1.28",
+			matching:   []string{"1.27.3", "1.28.9"},
+			unmatching: []string{"1.27.2", "1.29.0"},
+		},
+		{
+			rangeStr:   "1.26.x || >=1.30",
+			matching:   []string{"1.26.1", "1.31.0"},
+			unmatching: []string{"1.27.0", "1.29.4"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rangeStr, func(t *testing.T) {
+			r, err := common.ParseVersionRange(tt.rangeStr)
+			if err != nil {
+				t.Fatalf("Unexpected error parsing %q: %v", tt.rangeStr, err)
+			}
+			for _, v := range tt.matching {
+				if !r.Contains(semver.MustParse(v)) {
+					t.Errorf("Expected %s to be inside of %q", v, tt.rangeStr)
+				}
+			}
+			for _, v := range tt.unmatching {
+				if r.Contains(semver.MustParse(v)) {
+					t.Errorf("Expected %s to be outside of %q", v, tt.rangeStr)
+				}
+			}
+		})
+	}
+}
+
+func TestVersionRangeMinorBounds(t *testing.T) {
+	tests := []struct {
+		rangeStr string
+		lowest   string
+		highest  string
+	}{
+		{rangeStr: "1.28", lowest: "1.28.0", highest: "1.28.0"},
+		{rangeStr: ">=1.27 <1.29", lowest: "1.27.0", highest: "1.29.0"},
+		{rangeStr: ">= v1.27.3 <=1.28", lowest: "1.27.0", highest: "1.28.0"},
+		{rangeStr: ">1.28", lowest: "1.29.0"},
+		{rangeStr: ">1.28.4", lowest: "1.28.0"},
+		{rangeStr: "<1.30.0", highest: "1.30.0"},
+		{rangeStr: "1.26.x || >=1.30", lowest: "1.26.0"},
+		{rangeStr: "1.26 || 1.29.2", lowest: "1.26.0", highest: "1.29.0"},
+		{rangeStr: "!=1.28.3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rangeStr, func(t *testing.T) {
+			r, err := common.ParseVersionRange(tt.rangeStr)
+			if err != nil {
+				t.Fatalf("Unexpected error parsing %q: %v", tt.rangeStr, err)
+			}
+			lowest, highest := r.MinorBounds()
+			if actual := boundString(lowest); actual != tt.lowest {
+				t.Errorf("Expected lowest minor %q, got %q", tt.lowest, actual)
+			}
+			if actual := boundString(highest); actual != tt.highest {
+				t.Errorf("Expected highest minor %q, got %q", tt.highest, actual)
+			}
+		})
+	}
+}
+
+func boundString(bound *semver.Version) string {
+	if bound == nil {
+		return ""
+	}
+	return bound.String()
+}
+
+func TestParseVersionRangeInvalid(t *testing.T) {
+	if _, err := common.ParseVersionRange(">=latest"); err == nil {
+		t.Error("Expected an error")
+	}
+}
@@ -1,8 +1,11 @@
 package config
 
 import (
+	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/spf13/viper"
 
@@ -11,6 +14,10 @@ import (
 
 const DefaultTimeout = 5
 
+// DefaultServerVersionCacheTTL is how long the version of an API server
+// is cached by default.
+const DefaultServerVersionCacheTTL = "10m"
+
 // Cfg is used to retrieve the configuration of kuberlr.
 type Cfg struct {
 	Paths []string
@@ -28,17 +35,15 @@ func NewCfg() *Cfg {
 // Load reads the configuration files from disks and merges them.
 func (c *Cfg) Load() (*viper.Viper, error) {
 	v := viper.New()
-	v.SetDefault("AllowDownload", true)
-	v.SetDefault("SystemPath", common.SystemPath)
-	v.SetDefault("Timeout", DefaultTimeout)
-	v.SetDefault("KubeMirrorUrl", "https://dl.k8s.io")
-	v.SetDefault("UseLatestIfNoCompatible", false)
+	for _, s := range settings {
+		v.SetDefault(s.Name, s.Default)
+	}
 
 	v.SetConfigType("toml")
 
 	// read environment variables, they take precedence
 	v.AutomaticEnv()
-	v.SetEnvPrefix("KUBERLR")
+	v.SetEnvPrefix(EnvPrefix)
 	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
 
 	if len(c.Paths) == 0 {
@@ -64,6 +69,75 @@ func (c *Cfg) GetKubeMirrorURL() (string, error) {
 	return v.GetString("KubeMirrorUrl"), nil
 }
 
+// SystemPaths returns the directories where the system-wide kubectl
+// binaries are looked for. The `SystemPath` setting can be either a
+// list of directories separated by the OS path list separator, or an
+// array of directories. When `ScanPATH` is enabled, all the entries of
+// the `PATH` environment variable are appended to the list.
+func SystemPaths(v *viper.Viper) []string {
+	paths := ConfiguredSystemPaths(v)
+	if v.GetBool("ScanPATH") {
+		paths = uniquePaths(append(paths, filepath.SplitList(os.Getenv("PATH"))...))
+	}
+
+	return paths
+}
+
+// ConfiguredSystemPaths returns the directories set by the `SystemPath`
+// setting, without the ones added by `ScanPATH`. They are the only
+// system-wide directories whose binaries kuberlr is allowed to remove.
+func ConfiguredSystemPaths(v *viper.Viper) []string {
+	if value, isString := v.Get("SystemPath").(string); isString {
+		return uniquePaths(filepath.SplitList(value))
+	}
+
+	return uniquePaths(v.GetStringSlice("SystemPath"))
+}
+
+// uniquePaths cleans the given paths and removes the duplicated ones.
+func uniquePaths(paths []string) []string {
+	seen := map[string]bool{}
+	unique := []string{}
+	for _, path := range paths {
+		path = filepath.Clean(path)
+		if path == "." || seen[path] {
+			// `.` comes from empty PATH entries, which are ignored
+			continue
+		}
+		seen[path] = true
+		unique = append(unique, path)
+	}
+
+	return unique
+}
+
+// ServerVersionCacheTTL returns how long the version of an API server is
+// cached. The `ServerVersionCacheTTL` setting is a duration like `10m` or
+// `1h30m`, `0` disables the cache.
+func ServerVersionCacheTTL(v *viper.Viper) (time.Duration, error) {
+	ttl, err := time.ParseDuration(v.GetString("ServerVersionCacheTTL"))
+	if err != nil {
+		return 0, fmt.Errorf("invalid ServerVersionCacheTTL: %w", err)
+	}
+	if ttl < 0 {
+		return 0, fmt.Errorf("invalid ServerVersionCacheTTL: %s is negative", ttl)
+	}
+
+	return ttl, nil
+}
+
+// MaxCacheSize returns the maximum size, in bytes, of the kubectl binaries
+// downloaded by kuberlr. The `MaxCacheSize` setting is either a number of
+// bytes or a size like `500MB` or `2GiB`, `0` means no limit.
+func MaxCacheSize(v *viper.Viper) (int64, error) {
+	size, err := common.ParseSize(v.GetString("MaxCacheSize"))
+	if err != nil {
+		return 0, fmt.Errorf("invalid MaxCacheSize: %w", err)
+	}
+
+	return size, nil
+}
+
 func mergeConfig(v *viper.Viper, cfgFile string) error {
 	_, err := os.Stat(cfgFile)
 	if err != nil {
@@ -3,7 +3,9 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
 type testData struct {
@@ -198,3 +200,175 @@ AllowDownload = true
 			v.GetString("SystemPath"), "global")
 	}
 }
+
+func TestSystemPaths(t *testing.T) {
+	tests := []struct {
+		name               string
+		config             string
+		path               string
+		expected           []string
+		expectedConfigured []string
+	}{
+		{
+			name:               "default",
+			config:             "",
+			expected:           []string{"/usr/bin"},
+			expectedConfigured: []string{"/usr/bin"},
+		},
+		{
+			name:               "path list",
+			config:             `SystemPath = "/usr/bin` + string(os.PathListSeparator) + `/usr/local/bin"`,
+			expected:           []string{"/usr/bin", "/usr/local/bin"},
+			expectedConfigured: []string{"/usr/bin", "/usr/local/bin"},
+		},
+		{
+			name:               "array",
+			config:             `SystemPath = ["/usr/bin", "/opt/kubernetes/bin", "/usr/bin"]`,
+			expected:           []string{"/usr/bin", "/opt/kubernetes/bin"},
+			expectedConfigured: []string{"/usr/bin", "/opt/kubernetes/bin"},
+		},
+		{
+			name: "scan PATH",
+			config: `
+SystemPath = ["/usr/bin"]
+ScanPATH = true
+`,
+			path:               "/home/user/bin" + string(os.PathListSeparator) + string(os.PathListSeparator) + "/usr/bin/",
+			expected:           []string{"/usr/bin", "/home/user/bin"},
+			expectedConfigured: []string{"/usr/bin"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			td, err := setup()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer teardown(td)
+
+			if err = writeConfig(td.FakeHome, tt.config); err != nil {
+				t.Fatal(err)
+			}
+			t.Setenv("PATH", tt.path)
+
+			c := Cfg{
+				Paths: []string{filepath.Join(td.FakeHome, "kuberlr.conf")},
+			}
+			v, err := c.Load()
+			if err != nil {
+				t.Fatalf("Unexpected error loading config: %v", err)
+			}
+
+			actual := SystemPaths(v)
+			if strings.Join(actual, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("Wrong system paths: got %v instead of %v", actual, tt.expected)
+			}
+			actual = ConfiguredSystemPaths(v)
+			if strings.Join(actual, ",") != strings.Join(tt.expectedConfigured, ",") {
+				t.Errorf("Wrong configured system paths: got %v instead of %v", actual, tt.expectedConfigured)
+			}
+		})
+	}
+}
+
+func TestServerVersionCacheTTL(t *testing.T) {
+	tests := []struct {
+		name      string
+		config    string
+		expected  time.Duration
+		expectErr bool
+	}{
+		{name: "default", config: "", expected: 10 * time.Minute},
+		{name: "custom", config: `ServerVersionCacheTTL = "1h30m"`, expected: 90 * time.Minute},
+		{name: "disabled", config: `ServerVersionCacheTTL = "0"`, expected: 0},
+		{name: "invalid", config: `ServerVersionCacheTTL = "tomorrow"`, expectErr: true},
+		{name: "negative", config: `ServerVersionCacheTTL = "-1m"`, expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			td, err := setup()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer teardown(td)
+
+			if err = writeConfig(td.FakeHome, tt.config); err != nil {
+				t.Fatal(err)
+			}
+
+			c := Cfg{
+				Paths: []string{filepath.Join(td.FakeHome, "kuberlr.conf")},
+			}
+			v, err := c.Load()
+			if err != nil {
+				t.Fatalf("Unexpected error loading config: %v", err)
+			}
+
+			actual, err := ServerVersionCacheTTL(v)
+			if tt.expectErr {
+				if err == nil {
+					t.Errorf("Expected an error, got TTL %s", actual)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if actual != tt.expected {
+				t.Errorf("Wrong TTL: got %s instead of %s", actual, tt.expected)
+			}
+		})
+	}
+}
+
+func TestMaxCacheSize(t *testing.T) {
+	tests := []struct {
+		name      string
+		config    string
+		expected  int64
+		expectErr bool
+	}{
+		{name: "default", config: "", expected: 0},
+		{name: "bytes", config: `MaxCacheSize = 1048576`, expected: 1048576},
+		{name: "unit", config: `MaxCacheSize = "500MiB"`, expected: 500 << 20},
+		{name: "invalid", config: `MaxCacheSize = "a lot"`, expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			td, err := setup()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer teardown(td)
+
+			if err = writeConfig(td.FakeHome, tt.config); err != nil {
+				t.Fatal(err)
+			}
+
+			c := Cfg{
+				Paths: []string{filepath.Join(td.FakeHome, "kuberlr.conf")},
+			}
+			v, err := c.Load()
+			if err != nil {
+				t.Fatalf("Unexpected error loading config: %v", err)
+			}
+
+			actual, err := MaxCacheSize(v)
+			if tt.expectErr {
+				if err == nil {
+					t.Errorf("Expected an error, got size %d", actual)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if actual != tt.expected {
+				t.Errorf("Wrong size: got %d instead of %d", actual, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,235 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/blang/semver/v4"
+	"github.com/spf13/viper"
+)
+
+// ContextsKey is the name of the configuration table holding the
+// per-context rules.
+const ContextsKey = "contexts"
+
+// ContextRule holds the settings defined inside of a `[contexts."<name>"]`
+// table of the configuration file. These settings are applied when the
+// kubeconfig context being used matches the rule.
+type ContextRule struct {
+	// Name is the key of the table. Unless Context is set, it is also the
+	// pattern matched against the name of the kubeconfig context.
+	// Note well: keys are case insensitive, viper turns them to lower case.
+	// Because of that, regular expressions taken from the key are matched
+	// in a case insensitive way and cannot use upper case escapes like `\D`.
+	Name string `mapstructure:"-"`
+
+	// Context is an optional pattern matched against the name of the
+	// kubeconfig context. It takes precedence over Name.
+	Context string `mapstructure:"Context"`
+
+	// Server is an optional pattern matched against the URL of the
+	// cluster referenced by the kubeconfig context.
+	Server string `mapstructure:"Server"`
+
+	// KubectlVersion, when set, is the exact version of kubectl to use,
+	// the API server is not queried.
+	KubectlVersion string `mapstructure:"KubectlVersion"`
+
+	// AllowDownload overrides the global `AllowDownload` setting.
+	AllowDownload *bool `mapstructure:"AllowDownload"`
+
+	// KubeMirrorURL overrides the global `KubeMirrorUrl` setting.
+	KubeMirrorURL string `mapstructure:"KubeMirrorUrl"`
+
+	// SkewPolicy overrides the global `SkewPolicy` setting.
+	SkewPolicy string `mapstructure:"SkewPolicy"`
+
+	// SkewMinorsBelow overrides the global `SkewMinorsBelow` setting.
+	SkewMinorsBelow *uint64 `mapstructure:"SkewMinorsBelow"`
+
+	// SkewMinorsAbove overrides the global `SkewMinorsAbove` setting.
+	SkewMinorsAbove *uint64 `mapstructure:"SkewMinorsAbove"`
+}
+
+// ContextRules is a list of ContextRule objects.
+type ContextRules []ContextRule
+
+// LoadContextRules returns the context rules defined inside of the
+// given configuration. The rules are sorted by precedence: rules with
+// an exact context name come first, followed by the ones with the
+// longest pattern.
+func LoadContextRules(v *viper.Viper) (ContextRules, error) {
+	rulesByName := map[string]ContextRule{}
+	if err := v.UnmarshalKey(ContextsKey, &rulesByName); err != nil {
+		return nil, fmt.Errorf("cannot parse the %q table: %w", ContextsKey, err)
+	}
+
+	rules := ContextRules{}
+	for name, rule := range rulesByName {
+		rule.Name = name
+		if _, err := compilePattern(rule.contextPattern(), rule.Context == ""); err != nil {
+			return nil, fmt.Errorf("invalid context pattern of rule %q: %w", name, err)
+		}
+		if _, err := compilePattern(rule.Server, false); err != nil {
+			return nil, fmt.Errorf("invalid server pattern of rule %q: %w", name, err)
+		}
+		if rule.KubectlVersion != "" {
+			if _, err := semver.ParseTolerant(rule.KubectlVersion); err != nil {
+				return nil, fmt.Errorf("invalid kubectl version of rule %q: %w", name, err)
+			}
+		}
+		rules = append(rules, rule)
+	}
+
+	sort.Slice(rules, func(i, j int) bool {
+		iExact := !isPattern(rules[i].contextPattern())
+		jExact := !isPattern(rules[j].contextPattern())
+		if iExact != jExact {
+			return iExact
+		}
+		if len(rules[i].contextPattern()) != len(rules[j].contextPattern()) {
+			return len(rules[i].contextPattern()) > len(rules[j].contextPattern())
+		}
+		return rules[i].Name < rules[j].Name
+	})
+
+	return rules, nil
+}
+
+// Match returns the first rule matching the given context name and cluster
+// server URL. The boolean is false when no rule matches.
+func (rules ContextRules) Match(contextName, server string) (ContextRule, bool) {
+	for _, rule := range rules {
+		if rule.Matches(contextName, server) {
+			return rule, true
+		}
+	}
+
+	return ContextRule{}, false
+}
+
+// Matches returns true when the rule applies to the given context name and
+// cluster server URL.
+func (r ContextRule) Matches(contextName, server string) bool {
+	// the key has been turned to lower case by viper, the case of the
+	// regular expression cannot be trusted
+	if !matchPattern(r.contextPattern(), contextName, r.Context == "") {
+		return false
+	}
+
+	return r.Server == "" || matchPattern(r.Server, server, false)
+}
+
+// String returns a human description of the rule.
+func (r ContextRule) String() string {
+	desc := fmt.Sprintf("contexts.%q", r.Name)
+	if r.Context != "" {
+		desc += fmt.Sprintf(" (context %q)", r.Context)
+	}
+	if r.Server != "" {
+		desc += fmt.Sprintf(" (server %q)", r.Server)
+	}
+	return desc
+}
+
+func (r ContextRule) contextPattern() string {
+	if r.Context != "" {
+		return r.Context
+	}
+	return r.Name
+}
+
+// isRegexp returns true when the pattern is a regular expression, these
+// are written between slashes: `/^prod-.*$/`.
+func isRegexp(pattern string) bool {
+	return len(pattern) > 1 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/")
+}
+
+func isPattern(pattern string) bool {
+	return isRegexp(pattern) || strings.ContainsAny(pattern, "*?[")
+}
+
+// compilePattern turns the given glob or regular expression into a
+// regular expression. Globs, and plain names, are always matched in a case
+// insensitive way. Regular expressions are matched in a case insensitive way
+// only when foldCase is set, this is required by the patterns taken from
+// the table keys because viper turns them to lower case.
+func compilePattern(pattern string, foldCase bool) (*regexp.Regexp, error) {
+	if isRegexp(pattern) {
+		expr := pattern[1 : len(pattern)-1]
+		if foldCase {
+			expr = "(?i)" + expr
+		}
+		return regexp.Compile(expr)
+	}
+
+	expr, err := globToRegexp(pattern)
+	if err != nil {
+		return nil, err
+	}
+	return regexp.Compile("(?i)^" + expr + "$")
+}
+
+// globToRegexp translates a glob into a regular expression. Unlike
+// path.Match, `*` matches also `/`: this allows to match URLs
+// (`https://*.example.com`) and names like EKS ARNs
+// (`arn:aws:eks:*:cluster/prod-*`).
+func globToRegexp(glob string) (string, error) {
+	var expr strings.Builder
+
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; c {
+		case '*':
+			expr.WriteString(".*")
+		case '?':
+			expr.WriteString(".")
+		case '\\':
+			if i+1 == len(glob) {
+				return "", fmt.Errorf("trailing escape character in %q", glob)
+			}
+			i++
+			expr.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		case '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 1 {
+				return "", fmt.Errorf("unterminated character class in %q", glob)
+			}
+			class := glob[i+1 : i+1+end]
+			if class[0] == '!' || class[0] == '^' {
+				class = "^" + class[1:]
+			}
+			expr.WriteString("[" + class + "]")
+			i += end + 1
+		default:
+			expr.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		}
+	}
+
+	return expr.String(), nil
+}
+
+// MatchPattern matches the given value like the context rules do: the
+// pattern can be an exact name, a glob (`prod-*`) or a regular expression
+// written between slashes (`/^prod-[0-9]+$/`). Names and globs are matched
+// in a case insensitive way, regular expressions are case sensitive.
+func MatchPattern(pattern, value string) (bool, error) {
+	re, err := compilePattern(pattern, false)
+	if err != nil {
+		return false, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+	}
+
+	return re.MatchString(value), nil
+}
+
+// matchPattern matches the given value against a glob or a regular
+// expression, invalid patterns never match.
+func matchPattern(pattern, value string, foldCase bool) bool {
+	re, err := compilePattern(pattern, foldCase)
+	if err != nil {
+		return false
+	}
+
+	return re.MatchString(value)
+}
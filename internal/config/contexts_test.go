@@ -0,0 +1,216 @@
+package config
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func loadTestContextRules(t *testing.T, data string) ContextRules {
+	t.Helper()
+
+	td, err := setup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer teardown(td)
+
+	if err = writeConfig(td.FakeHome, data); err != nil {
+		t.Fatal(err)
+	}
+
+	c := Cfg{
+		Paths: []string{filepath.Join(td.FakeHome, "kuberlr.conf")},
+	}
+	v, err := c.Load()
+	if err != nil {
+		t.Fatalf("Unexpected error loading config: %v", err)
+	}
+
+	rules, err := LoadContextRules(v)
+	if err != nil {
+		t.Fatalf("Unexpected error loading context rules: %v", err)
+	}
+	return rules
+}
+
+func TestContextRulesMatch(t *testing.T) {
+	rules := loadTestContextRules(t, `
+[contexts."prod-*"]
+KubectlVersion = "1.28.4"
+AllowDownload = false
+
+[contexts."prod-eu.example"]
+KubectlVersion = "1.27"
+
+[contexts.staging]
+Context = "/^stag(e|ing)-[0-9]+$/"
+Server = "https://*.staging.example.com"
+KubeMirrorUrl = "https://mirror.example.com"
+
+[contexts."arn:aws:eks:*:cluster/prod-*"]
+KubectlVersion = "1.29.0"
+
+[contexts."/^Team-[A-Z]+$/"]
+KubectlVersion = "1.30.0"
+
+[contexts.production]
+Context = "*"
+Server = "*prod*"
+`)
+
+	tests := []struct {
+		name         string
+		context      string
+		server       string
+		expectedRule string
+	}{
+		{
+			name:         "exact name wins over glob",
+			context:      "prod-eu.example",
+			expectedRule: "prod-eu.example",
+		},
+		{
+			name:         "glob on context name",
+			context:      "Prod-US",
+			expectedRule: "prod-*",
+		},
+		{
+			name:         "regexp on context name and glob on server",
+			context:      "staging-42",
+			server:       "https://api.staging.example.com",
+			expectedRule: "staging",
+		},
+		{
+			name:         "server does not match",
+			context:      "staging-42",
+			server:       "https://api.example.com",
+			expectedRule: "",
+		},
+		{
+			name:         "glob on an EKS ARN",
+			context:      "arn:aws:eks:eu-west-1:123456789012:cluster/prod-eu",
+			expectedRule: "arn:aws:eks:*:cluster/prod-*",
+		},
+		{
+			name:         "upper case regexp taken from the key",
+			context:      "Team-ABC",
+			expectedRule: "/^team-[a-z]+$/",
+		},
+		{
+			name:         "glob on server URL",
+			context:      "dev",
+			server:       "https://api.prod.example.com:6443",
+			expectedRule: "production",
+		},
+		{
+			name:         "nothing matches",
+			context:      "dev",
+			expectedRule: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, found := rules.Match(tt.context, tt.server)
+			if tt.expectedRule == "" {
+				if found {
+					t.Errorf("Expected no rule to match, got %s", rule)
+				}
+				return
+			}
+			if !found {
+				t.Fatalf("Expected rule %q to match, nothing matched", tt.expectedRule)
+			}
+			if rule.Name != tt.expectedRule {
+				t.Errorf("Expected rule %q to match, got %q", tt.expectedRule, rule.Name)
+			}
+		})
+	}
+}
+
+func TestContextRuleSettings(t *testing.T) {
+	rules := loadTestContextRules(t, `
+[contexts."prod-*"]
+KubectlVersion = "1.28.4"
+AllowDownload = false
+KubeMirrorUrl = "https://mirror.example.com"
+`)
+
+	rule, found := rules.Match("prod-1", "")
+	if !found {
+		t.Fatal("Expected rule to match")
+	}
+	if rule.KubectlVersion != "1.28.4" {
+		t.Errorf("Wrong KubectlVersion: got %q", rule.KubectlVersion)
+	}
+	if rule.AllowDownload == nil || *rule.AllowDownload {
+		t.Errorf("Wrong AllowDownload: got %v", rule.AllowDownload)
+	}
+	if rule.KubeMirrorURL != "https://mirror.example.com" {
+		t.Errorf("Wrong KubeMirrorUrl: got %q", rule.KubeMirrorURL)
+	}
+}
+
+func TestContextRulesInvalidVersion(t *testing.T) {
+	td, err := setup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer teardown(td)
+
+	err = writeConfig(td.FakeHome, `
+[contexts.prod]
+KubectlVersion = "latest"
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := Cfg{
+		Paths: []string{filepath.Join(td.FakeHome, "kuberlr.conf")},
+	}
+	v, err := c.Load()
+	if err != nil {
+		t.Fatalf("Unexpected error loading config: %v", err)
+	}
+
+	if _, err = LoadContextRules(v); err == nil {
+		t.Error("Expected an error because of the invalid version")
+	}
+}
+
+func TestMatchPattern(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		value    string
+		expected bool
+	}{
+		{pattern: "prod", value: "prod", expected: true},
+		{pattern: "prod", value: "prod-1", expected: false},
+		{pattern: "prod-*", value: "PROD-1", expected: true},
+		{pattern: "/^prod-[0-9]+$/", value: "prod-12", expected: true},
+		{pattern: "/^prod-[0-9]+$/", value: "prod-eu", expected: false},
+		{pattern: "/^PROD-\\D+$/", value: "PROD-eu", expected: true},
+		{pattern: "/^PROD-\\D+$/", value: "prod-eu", expected: false},
+		{pattern: "https://*.example.com", value: "https://api.prod.example.com", expected: true},
+		{pattern: "prod-[!0-9]?", value: "prod-eu", expected: true},
+		{pattern: "prod-[!0-9]?", value: "prod-1u", expected: false},
+	}
+
+	for _, tt := range tests {
+		matched, err := MatchPattern(tt.pattern, tt.value)
+		if err != nil {
+			t.Errorf("%q: unexpected error %v", tt.pattern, err)
+			continue
+		}
+		if matched != tt.expected {
+			t.Errorf("%q against %q: expected %v, got %v", tt.pattern, tt.value, tt.expected, matched)
+		}
+	}
+
+	for _, pattern := range []string{"/prod-(/", "prod-["} {
+		if _, err := MatchPattern(pattern, "prod"); err == nil {
+			t.Errorf("%q: expected an error", pattern)
+		}
+	}
+}
@@ -0,0 +1,531 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/pelletier/go-toml/v2"
+
+	"github.com/flavio/kuberlr/internal/common"
+)
+
+// EnvPrefix is the prefix of the environment variables overriding the
+// settings.
+const EnvPrefix = "KUBERLR"
+
+// valueKind is the type of the value of a setting.
+type valueKind int
+
+const (
+	boolValue valueKind = iota
+	intValue
+	stringValue
+	// stringListValue is an array of strings
+	stringListValue
+	// pathListValue is either a path list or an array of strings
+	pathListValue
+	// stringOrIntValue is either a string or an integer, like `"10m"`
+	// or `0`
+	stringOrIntValue
+)
+
+// setting describes a key of the configuration files.
+type setting struct {
+	Name    string
+	Kind    valueKind
+	Default any
+}
+
+// settings lists the top-level keys of the configuration files, with their
+// default values.
+var settings = []setting{ //nolint: gochecknoglobals // arrays cannot be go constants
+	{Name: "AllowDownload", Kind: boolValue, Default: true},
+	{Name: "UseLatestIfNoCompatible", Kind: boolValue, Default: false},
+	{Name: "SkewPolicy", Kind: stringValue, Default: "upstream"},
+	{Name: "SkewMinorsBelow", Kind: intValue, Default: 1},
+	{Name: "SkewMinorsAbove", Kind: intValue, Default: 1},
+	{Name: "Ranking", Kind: stringListValue, Default: []string{"exact-minor", "closest-patch", "local", "recently-used"}},
+	{Name: "SystemPath", Kind: pathListValue, Default: common.SystemPath},
+	{Name: "ScanPATH", Kind: boolValue, Default: false},
+	{Name: "Timeout", Kind: intValue, Default: DefaultTimeout},
+	{Name: "ServerVersionCacheTTL", Kind: stringOrIntValue, Default: DefaultServerVersionCacheTTL},
+	{Name: "MaxCacheSize", Kind: stringOrIntValue, Default: "0"},
+	{Name: "KubeMirrorUrl", Kind: stringValue, Default: "https://dl.k8s.io"},
+}
+
+// contextRuleSettings lists the keys of the `[contexts."<name>"]` tables.
+var contextRuleSettings = []setting{ //nolint: gochecknoglobals // arrays cannot be go constants
+	{Name: "Context", Kind: stringValue},
+	{Name: "Server", Kind: stringValue},
+	{Name: "KubectlVersion", Kind: stringValue},
+	{Name: "AllowDownload", Kind: boolValue},
+	{Name: "KubeMirrorUrl", Kind: stringValue},
+	{Name: "SkewPolicy", Kind: stringValue},
+	{Name: "SkewMinorsBelow", Kind: intValue},
+	{Name: "SkewMinorsAbove", Kind: intValue},
+}
+
+// Origin describes where the effective value of a setting comes from.
+type Origin struct {
+	Key   string
+	Value any
+	// Source is either `file:<path>`, `env:<name>` or `default`
+	Source string
+}
+
+// UserConfigFile returns the path of the configuration file of the user.
+func UserConfigFile() string {
+	return filepath.Join(common.HomeDir(), ".kuberlr", "kuberlr.conf")
+}
+
+// EnvName returns the name of the environment variable overriding the
+// given setting.
+func EnvName(key string) string {
+	return EnvPrefix + "_" + strings.ToUpper(key)
+}
+
+// SettingNames returns the names of the top-level settings, the ones that
+// can be changed with SetValue.
+func SettingNames() []string {
+	names := make([]string, len(settings))
+	for i, s := range settings {
+		names[i] = s.Name
+	}
+
+	return names
+}
+
+// Origins returns the effective value of all the settings, including the
+// ones of the context rules, together with the place they come from.
+func (c *Cfg) Origins() ([]Origin, error) {
+	v, err := c.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	files := map[string]map[string]any{}
+	for _, path := range c.Paths {
+		data, readErr := readConfigFile(path)
+		if readErr != nil {
+			return nil, readErr
+		}
+		if data != nil {
+			files[path] = data
+		}
+	}
+
+	origins := []Origin{}
+	for _, s := range settings {
+		origin := Origin{Key: s.Name, Value: v.Get(s.Name), Source: "default"}
+		for _, path := range c.Paths {
+			if _, found := lookupKey(files[path], s.Name); found {
+				origin.Source = "file:" + path
+			}
+		}
+		if value, found := os.LookupEnv(EnvName(s.Name)); found && value != "" {
+			origin.Source = "env:" + EnvName(s.Name)
+		}
+		origins = append(origins, origin)
+	}
+
+	// the rules are merged like viper does: the last file wins
+	ruleOrigins := map[string]Origin{}
+	for _, path := range c.Paths {
+		rules, _ := lookupKey(files[path], ContextsKey)
+		tables, _ := rules.(map[string]any)
+		for name, table := range tables {
+			values, _ := table.(map[string]any)
+			for key, value := range values {
+				id := strings.ToLower(name + "\x00" + key)
+				ruleOrigins[id] = Origin{
+					Key:    fmt.Sprintf("%s.%q.%s", ContextsKey, name, key),
+					Value:  value,
+					Source: "file:" + path,
+				}
+			}
+		}
+	}
+	ids := make([]string, 0, len(ruleOrigins))
+	for id := range ruleOrigins {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		origins = append(origins, ruleOrigins[id])
+	}
+
+	return origins, nil
+}
+
+// ValidateFile ensures the given configuration file is valid TOML, that it
+// defines only known settings and that their values have the right type.
+// All the problems found are returned.
+func ValidateFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	return validateData(path, data)
+}
+
+func validateData(path string, data []byte) error {
+	values := map[string]any{}
+	if err := toml.Unmarshal(data, &values); err != nil {
+		return decodeError(path, err)
+	}
+
+	errs := []error{}
+	for _, key := range sortedKeys(values) {
+		if strings.EqualFold(key, ContextsKey) {
+			errs = append(errs, validateContextRules(path, values[key])...)
+			continue
+		}
+		s, found := lookupSetting(settings, key)
+		if !found {
+			errs = append(errs, fmt.Errorf("%s: unknown setting %q", path, key))
+			continue
+		}
+		if err := checkKind(s, values[key]); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", path, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func validateContextRules(path string, value any) []error {
+	rules, isTable := value.(map[string]any)
+	if !isTable {
+		return []error{fmt.Errorf("%s: %q must be a table", path, ContextsKey)}
+	}
+
+	errs := []error{}
+	for _, name := range sortedKeys(rules) {
+		rule, isTable := rules[name].(map[string]any)
+		if !isTable {
+			errs = append(errs, fmt.Errorf("%s: %s.%q must be a table", path, ContextsKey, name))
+			continue
+		}
+		for _, key := range sortedKeys(rule) {
+			s, found := lookupSetting(contextRuleSettings, key)
+			if !found {
+				errs = append(errs, fmt.Errorf("%s: unknown setting %q of rule %s.%q", path, key, ContextsKey, name))
+				continue
+			}
+			if err := checkKind(s, rule[key]); err != nil {
+				errs = append(errs, fmt.Errorf("%s: rule %s.%q: %w", path, ContextsKey, name, err))
+			}
+		}
+	}
+
+	return errs
+}
+
+// checkKind ensures the value, as decoded from TOML, has the type expected
+// by the setting.
+func checkKind(s setting, value any) error {
+	valid := false
+	expected := ""
+	switch s.Kind {
+	case boolValue:
+		_, valid = value.(bool)
+		expected = "a boolean"
+	case intValue:
+		_, valid = value.(int64)
+		expected = "an integer"
+	case stringValue:
+		_, valid = value.(string)
+		expected = "a string"
+	case stringListValue:
+		valid = isStringList(value)
+		expected = "an array of strings"
+	case pathListValue:
+		_, valid = value.(string)
+		valid = valid || isStringList(value)
+		expected = "a string or an array of strings"
+	case stringOrIntValue:
+		_, valid = value.(string)
+		if _, isInt := value.(int64); isInt {
+			valid = true
+		}
+		expected = "a string or an integer"
+	}
+
+	if !valid {
+		return fmt.Errorf("%s must be %s, got %v", s.Name, expected, FormatValue(value))
+	}
+	return nil
+}
+
+func isStringList(value any) bool {
+	items, isList := value.([]any)
+	if !isList {
+		return false
+	}
+	for _, item := range items {
+		if _, isString := item.(string); !isString {
+			return false
+		}
+	}
+	return true
+}
+
+// SetValue changes a top-level setting of the given configuration file,
+// creating the file when it doesn't exist. The rest of the file, comments
+// included, is left untouched. The new content is written to a temporary
+// file, which replaces the original one only once the optional check
+// function has accepted it.
+func SetValue(path, key, value string, check func(candidate string) error) error {
+	s, found := lookupSetting(settings, key)
+	if !found {
+		if strings.HasPrefix(strings.ToLower(key), ContextsKey+".") {
+			return fmt.Errorf("the %s tables cannot be changed by kuberlr, edit %s instead", ContextsKey, path)
+		}
+		return fmt.Errorf("unknown setting %q", key)
+	}
+	parsed, err := parseValue(s, value)
+	if err != nil {
+		return err
+	}
+
+	mode := os.FileMode(0o600)
+	data, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		if info, statErr := os.Stat(path); statErr == nil {
+			mode = info.Mode().Perm()
+		}
+	case os.IsNotExist(err):
+		data = nil
+	default:
+		return err
+	}
+
+	current := map[string]any{}
+	if err = toml.Unmarshal(data, &current); err != nil {
+		return fmt.Errorf("fix the syntax of the file before changing it: %w", decodeError(path, err))
+	}
+	updated := setTopLevelKey(data, s.Name, s.Name+" = "+FormatValue(parsed))
+
+	// ensure nothing else has been changed by accident
+	expected := map[string]any{}
+	for k, v := range current {
+		if !strings.EqualFold(k, s.Name) {
+			expected[k] = v
+		}
+	}
+	expected[s.Name] = normalizeValue(parsed)
+	result := map[string]any{}
+	if err = toml.Unmarshal(updated, &result); err != nil || !reflect.DeepEqual(expected, result) {
+		return fmt.Errorf("%s cannot be changed safely inside of %s, edit the file instead", s.Name, path)
+	}
+
+	return replaceFile(path, updated, mode, check)
+}
+
+// parseValue converts the value given on the command line to the type
+// expected by the setting.
+func parseValue(s setting, value string) (any, error) {
+	switch s.Kind {
+	case boolValue:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be a boolean: %w", s.Name, err)
+		}
+		return b, nil
+	case intValue:
+		i, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be an integer: %w", s.Name, err)
+		}
+		return i, nil
+	case stringListValue:
+		items := []string{}
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		return items, nil
+	case stringValue, pathListValue, stringOrIntValue:
+	}
+
+	for _, r := range value {
+		if unicode.IsControl(r) {
+			return nil, fmt.Errorf("%s cannot contain control characters", s.Name)
+		}
+	}
+	return value, nil
+}
+
+// normalizeValue returns the value like it's decoded from TOML.
+func normalizeValue(value any) any {
+	items, isList := value.([]string)
+	if !isList {
+		return value
+	}
+
+	list := make([]any, 0, len(items))
+	for _, item := range items {
+		list = append(list, item)
+	}
+	return list
+}
+
+// FormatValue returns the TOML representation of a setting value.
+func FormatValue(value any) string {
+	switch v := value.(type) {
+	case string:
+		return strconv.Quote(v)
+	case []string:
+		return FormatValue(normalizeValue(v))
+	case []any:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			items = append(items, FormatValue(item))
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// setTopLevelKey replaces the line assigning the given top-level key with
+// the given one. When the key is not assigned, the line is added after the
+// last top-level assignment.
+func setTopLevelKey(data []byte, key, line string) []byte {
+	keyRe := regexp.MustCompile(`(?i)^\s*("?)` + regexp.QuoteMeta(key) + `("?)\s*=`)
+	assignmentRe := regexp.MustCompile(`^\s*"?[A-Za-z0-9_-]+"?\s*=`)
+
+	lines := strings.SplitAfter(string(data), "\n")
+	if len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) > 0 && !strings.HasSuffix(lines[len(lines)-1], "\n") {
+		lines[len(lines)-1] += "\n"
+	}
+
+	insertAt := -1
+	for i, l := range lines {
+		if strings.HasPrefix(strings.TrimSpace(l), "[") {
+			// the top-level keys are over once the first table starts
+			if insertAt == -1 {
+				insertAt = i
+			}
+			break
+		}
+		if keyRe.MatchString(l) {
+			lines[i] = line + "\n"
+			return []byte(strings.Join(lines, ""))
+		}
+		if assignmentRe.MatchString(l) {
+			insertAt = i + 1
+		}
+	}
+	if insertAt == -1 {
+		insertAt = len(lines)
+	}
+
+	lines = append(lines[:insertAt], append([]string{line + "\n"}, lines[insertAt:]...)...)
+	return []byte(strings.Join(lines, ""))
+}
+
+// replaceFile atomically replaces the file with the given content.
+func replaceFile(path string, data []byte, mode os.FileMode, check func(candidate string) error) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+"-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err = tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	if err = os.Chmod(tmp.Name(), mode); err != nil {
+		return err
+	}
+	if check != nil {
+		if err = check(tmp.Name()); err != nil {
+			return err
+		}
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// readConfigFile returns the content of the given configuration file, nil
+// when the file doesn't exist.
+func readConfigFile(path string) (map[string]any, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	values := map[string]any{}
+	if err = toml.Unmarshal(data, &values); err != nil {
+		return nil, decodeError(path, err)
+	}
+	return values, nil
+}
+
+func decodeError(path string, err error) error {
+	var decodeErr *toml.DecodeError
+	if errors.As(err, &decodeErr) {
+		row, column := decodeErr.Position()
+		return fmt.Errorf("%s:%d:%d: %w", path, row, column, err)
+	}
+	return fmt.Errorf("%s: %w", path, err)
+}
+
+// lookupKey returns the value of the given key, which is matched in a case
+// insensitive way like viper does.
+func lookupKey(values map[string]any, key string) (any, bool) {
+	for k, v := range values {
+		if strings.EqualFold(k, key) {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+func lookupSetting(list []setting, key string) (setting, bool) {
+	for _, s := range list {
+		if strings.EqualFold(s.Name, key) {
+			return s, true
+		}
+	}
+	return setting{}, false
+}
+
+func sortedKeys(values map[string]any) []string {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
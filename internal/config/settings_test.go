@@ -0,0 +1,278 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidateFile(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   string
+		errors []string
+	}{
+		{
+			name: "valid",
+			data: `
+AllowDownload = false
+timeout = 3
+SystemPath = ["/usr/bin", "/opt/bin"]
+MaxCacheSize = 0
+ServerVersionCacheTTL = "1h"
+
+[contexts."prod-*"]
+KubectlVersion = "1.28.4"
+SkewMinorsBelow = 0
+`,
+		},
+		{
+			name:   "unknown setting",
+			data:   "AllowDownloads = true\n",
+			errors: []string{`unknown setting "AllowDownloads"`},
+		},
+		{
+			name: "bad types",
+			data: `
+AllowDownload = "yes"
+Timeout = "5"
+Ranking = ["local", 1]
+`,
+			errors: []string{
+				`AllowDownload must be a boolean, got "yes"`,
+				`Ranking must be an array of strings, got ["local", 1]`,
+				`Timeout must be an integer, got "5"`,
+			},
+		},
+		{
+			name: "bad context rule",
+			data: `
+[contexts.dev]
+AllowDownload = 1
+KubectlVersions = "1.28"
+`,
+			errors: []string{
+				`rule contexts."dev": AllowDownload must be a boolean, got 1`,
+				`unknown setting "KubectlVersions" of rule contexts."dev"`,
+			},
+		},
+		{
+			name:   "syntax error",
+			data:   "Timeout = \n",
+			errors: []string{"kuberlr.conf:1:"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := writeConfig(dir, tc.data); err != nil {
+				t.Fatal(err)
+			}
+
+			err := ValidateFile(filepath.Join(dir, "kuberlr.conf"))
+			if len(tc.errors) == 0 {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			for _, expected := range tc.errors {
+				if !strings.Contains(err.Error(), expected) {
+					t.Errorf("expected %q inside of %q", expected, err.Error())
+				}
+			}
+		})
+	}
+}
+
+func TestOrigins(t *testing.T) {
+	td, err := setup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer teardown(td)
+
+	if err = writeConfig(td.FakeEtc, "Timeout = 3\nSkewPolicy = \"strict\"\n[contexts.dev]\nAllowDownload = true\n"); err != nil {
+		t.Fatal(err)
+	}
+	if err = writeConfig(td.FakeHome, "timeout = 7\n[contexts.dev]\nAllowDownload = false\n"); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("KUBERLR_SKEWPOLICY", "newer-only")
+
+	etcFile := filepath.Join(td.FakeEtc, "kuberlr.conf")
+	homeFile := filepath.Join(td.FakeHome, "kuberlr.conf")
+	c := Cfg{Paths: []string{etcFile, homeFile, filepath.Join(td.FakeUsrEtc, "missing.conf")}}
+
+	origins, err := c.Origins()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]Origin{
+		"Timeout":                      {Value: int64(7), Source: "file:" + homeFile},
+		"SkewPolicy":                   {Value: "newer-only", Source: "env:KUBERLR_SKEWPOLICY"},
+		"AllowDownload":                {Value: true, Source: "default"},
+		`contexts."dev".AllowDownload`: {Value: false, Source: "file:" + homeFile},
+	}
+	found := 0
+	for _, o := range origins {
+		e, ok := expected[o.Key]
+		if !ok {
+			continue
+		}
+		found++
+		if o.Value != e.Value || o.Source != e.Source {
+			t.Errorf("%s: expected %v from %s, got %v from %s", o.Key, e.Value, e.Source, o.Value, o.Source)
+		}
+	}
+	if found != len(expected) {
+		t.Errorf("expected %d origins, found %d: %+v", len(expected), found, origins)
+	}
+}
+
+func TestSetValue(t *testing.T) {
+	original := `# kuberlr settings
+AllowDownload = true # keep downloading
+timeout = 5
+
+# production rules
+[contexts."prod-*"]
+AllowDownload = false
+`
+
+	tests := []struct {
+		name     string
+		key      string
+		value    string
+		expected string
+		err      string
+	}{
+		{
+			name:  "replace",
+			key:   "Timeout",
+			value: "10",
+			expected: `# kuberlr settings
+AllowDownload = true # keep downloading
+Timeout = 10
+
+# production rules
+[contexts."prod-*"]
+AllowDownload = false
+`,
+		},
+		{
+			name:  "add",
+			key:   "ranking",
+			value: "local, exact-minor",
+			expected: `# kuberlr settings
+AllowDownload = true # keep downloading
+timeout = 5
+Ranking = ["local", "exact-minor"]
+
+# production rules
+[contexts."prod-*"]
+AllowDownload = false
+`,
+		},
+		{
+			name:  "bad value",
+			key:   "ScanPATH",
+			value: "maybe",
+			err:   "ScanPATH must be a boolean",
+		},
+		{
+			name:  "unknown setting",
+			key:   "Timeouts",
+			value: "1",
+			err:   `unknown setting "Timeouts"`,
+		},
+		{
+			name:  "context rule",
+			key:   "contexts.dev.AllowDownload",
+			value: "true",
+			err:   "cannot be changed by kuberlr",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "kuberlr.conf")
+			if err := os.WriteFile(path, []byte(original), 0o640); err != nil {
+				t.Fatal(err)
+			}
+
+			err := SetValue(path, tc.key, tc.value, nil)
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Errorf("expected error %q, got %v", tc.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != tc.expected {
+				t.Errorf("expected:\n%s\ngot:\n%s", tc.expected, data)
+			}
+			if info, _ := os.Stat(path); info.Mode().Perm() != 0o640 {
+				t.Errorf("expected permissions to be preserved, got %v", info.Mode().Perm())
+			}
+		})
+	}
+}
+
+func TestSetValueNewFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".kuberlr", "kuberlr.conf")
+
+	if err := SetValue(path, "KubeMirrorUrl", "https://mirror.example.com", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "KubeMirrorUrl = \"https://mirror.example.com\"\n" {
+		t.Errorf("unexpected content: %q", data)
+	}
+}
+
+func TestSetValueRejectedByCheck(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kuberlr.conf")
+	if err := os.WriteFile(path, []byte("SkewPolicy = \"strict\"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	err := SetValue(path, "SkewPolicy", "bogus", func(candidate string) error {
+		if err := ValidateFile(candidate); err != nil {
+			t.Errorf("unexpected validation error: %v", err)
+		}
+		return os.ErrInvalid
+	})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "SkewPolicy = \"strict\"\n" {
+		t.Errorf("the file has been changed: %q", data)
+	}
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	if len(entries) != 1 {
+		t.Errorf("temporary files left behind: %v", entries)
+	}
+}
@@ -10,7 +10,7 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
-	"runtime"
+	"regexp"
 	"strings"
 	"time"
 
@@ -23,28 +23,59 @@ import (
 	"k8s.io/klog"
 )
 
-func getKubeMirrorURL() (string, error) {
+var (
+	markerRe       = regexp.MustCompile(`^(stable|latest)(-\d+\.\d+)?$`) //nolint: gochecknoglobals // regexps cannot be go constants
+	minorVersionRe = regexp.MustCompile(`^v?\d+\.\d+$`)                  //nolint: gochecknoglobals // regexps cannot be go constants
+)
+
+// Downloder is a helper class that is used to interact with the
+// kubernetes infrastructure holding released binaries and release information.
+type Downloder struct {
+	// KubeMirrorURL is the URL of the mirror to use. When empty, the
+	// value is read from kuberlr's configuration.
+	KubeMirrorURL string
+	// Timeout limits the requests fetching release information, like the
+	// latest stable version. Zero means no timeout.
+	Timeout time.Duration
+}
+
+func (d *Downloder) getKubeMirrorURL() (string, error) {
+	if d.KubeMirrorURL != "" {
+		return d.KubeMirrorURL, nil
+	}
+
 	cfg := config.NewCfg()
 	return cfg.GetKubeMirrorURL()
 }
 
-// Downloder is a helper class that is used to interact with the
-// kubernetes infrastructure holding released binaries and release information.
-type Downloder struct{}
+// httpStatusError is returned when the server answers with an unexpected
+// HTTP status.
+type httpStatusError struct {
+	url        string
+	status     string
+	statusCode int
+}
+
+func (e *httpStatusError) Error() string {
+	return fmt.Sprintf("GET %s returned http status %s", e.url, e.status)
+}
+
+// isNotFound returns true when the requested resource doesn't exist.
+func isNotFound(err error) bool {
+	var statusErr *httpStatusError
+	return errors.As(err, &statusErr) && statusErr.statusCode == http.StatusNotFound
+}
 
 func (d *Downloder) getContentsOfURL(url string) (string, error) {
+	client := &http.Client{Timeout: d.Timeout}
 	//nolint: gosec,noctx // the url is built internally
-	res, err := http.Get(url)
+	res, err := client.Get(url)
 	if err != nil {
 		return "", err
 	}
 	if res.StatusCode != http.StatusOK {
-		return "",
-			fmt.Errorf(
-				"GET %s returned http status %s",
-				url,
-				res.Status,
-			)
+		res.Body.Close()
+		return "", &httpStatusError{url: url, status: res.Status, statusCode: res.StatusCode}
 	}
 
 	v, err := io.ReadAll(res.Body)
@@ -62,11 +93,49 @@ func (d *Downloder) getContentsOfURL(url string) (string, error) {
 // UpstreamStableVersion returns the latest version of kubernetes that upstream
 // considers stable.
 func (d *Downloder) UpstreamStableVersion() (semver.Version, error) {
-	baseURL, err := getKubeMirrorURL()
+	return d.markerVersion("stable")
+}
+
+// ResolveVersion returns the kubernetes version identified by the given
+// selector, which can be:
+//   - a full version, like `1.28.4` or `v1.31.0-rc.1`
+//   - a minor version, like `1.28`: the latest 1.28.x release
+//   - `stable` or `latest`: the latest stable release and the latest
+//     release, including pre-releases
+//   - `stable-1.28` or `latest-1.28`: the same, restricted to a minor version
+//   - a range, like `>=1.27 <1.29`: the most recent stable release inside of
+//     the range
+func (d *Downloder) ResolveVersion(selector string) (semver.Version, error) {
+	selector = strings.TrimSpace(selector)
+
+	switch {
+	case markerRe.MatchString(selector):
+		return d.markerVersion(selector)
+	case minorVersionRe.MatchString(selector):
+		return d.markerVersion("latest-" + strings.TrimPrefix(selector, "v"))
+	}
+
+	if version, err := semver.Parse(strings.TrimPrefix(selector, "v")); err == nil {
+		return version, nil
+	}
+
+	versionRange, err := common.ParseVersionRange(selector)
+	if err != nil {
+		return semver.Version{}, fmt.Errorf(
+			"invalid version selector %q: it must be a version, a range, stable, latest, stable-<minor> or latest-<minor>",
+			selector)
+	}
+	return d.LatestVersionInRange(versionRange)
+}
+
+// markerVersion returns the version written inside of the given marker
+// file of the mirror, like `stable.txt` or `latest-1.28.txt`.
+func (d *Downloder) markerVersion(marker string) (semver.Version, error) {
+	baseURL, err := d.getKubeMirrorURL()
 	if err != nil {
 		return semver.Version{}, err
 	}
-	url, err := url.Parse(baseURL + "/release/stable.txt")
+	url, err := url.Parse(baseURL + "/release/" + marker + ".txt")
 	if err != nil {
 		return semver.Version{}, err
 	}
@@ -75,7 +144,40 @@ func (d *Downloder) UpstreamStableVersion() (semver.Version, error) {
 	if err != nil {
 		return semver.Version{}, err
 	}
-	return semver.ParseTolerant(v)
+	return semver.ParseTolerant(strings.TrimSpace(v))
+}
+
+// LatestVersionInRange returns the most recent stable release inside of the
+// given range. The minor versions matched by the range are walked
+// backwards, starting from the latest stable release; all the patch
+// releases up to the latest one of each minor version are assumed to exist.
+func (d *Downloder) LatestVersionInRange(versionRange *common.VersionRange) (semver.Version, error) {
+	var version *semver.Version
+
+	lowest, highest := versionRange.MinorBounds()
+	err := d.walkMinorVersions(lowest, highest, func(major, minor uint64) (bool, error) {
+		latest, err := d.markerVersion(fmt.Sprintf("stable-%d.%d", major, minor))
+		if err != nil {
+			return false, err
+		}
+
+		for patch := latest.Patch + 1; patch > 0; patch-- {
+			candidate := semver.Version{Major: latest.Major, Minor: latest.Minor, Patch: patch - 1}
+			if versionRange.Contains(candidate) {
+				version = &candidate
+				return false, nil
+			}
+		}
+		return true, nil
+	})
+	if err != nil {
+		return semver.Version{}, err
+	}
+	if version == nil {
+		return semver.Version{}, errors.New("no stable release inside of the requested range")
+	}
+
+	return *version, nil
 }
 
 // GetKubectlBinary downloads the kubectl binary identified by the given version
@@ -125,20 +227,22 @@ func (d *Downloder) GetKubectlBinary(version semver.Version, destination string)
 }
 
 func (d *Downloder) kubectlDownloadURL(version semver.Version) (string, error) {
+	return d.kubectlPlatformDownloadURL(version, HostPlatform())
+}
+
+func (d *Downloder) kubectlPlatformDownloadURL(version semver.Version, platform Platform) (string, error) {
 	// Example: https://storage.googleapis.com/kubernetes-release/release/v1.18.0/bin/linux/amd64/kubectlI
-	baseURL, err := getKubeMirrorURL()
+	baseURL, err := d.getKubeMirrorURL()
 	if err != nil {
 		return "", err
 	}
 	url, err := url.Parse(fmt.Sprintf(
-		"%s/release/v%d.%d.%d/bin/%s/%s/kubectl%s",
+		"%s/release/v%s/bin/%s/%s/kubectl%s",
 		baseURL,
-		version.Major,
-		version.Minor,
-		version.Patch,
-		runtime.GOOS,
-		runtime.GOARCH,
-		osexec.Ext,
+		common.ReleaseVersion(version).String(),
+		platform.OS,
+		platform.Arch,
+		platform.binaryExt(),
 	))
 	if err != nil {
 		return "", err
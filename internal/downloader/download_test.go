@@ -0,0 +1,267 @@
+package downloader
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/blang/semver/v4"
+
+	"github.com/flavio/kuberlr/internal/common"
+)
+
+func newFakeMirror(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	return newRecordingFakeMirror(t, nil)
+}
+
+// newRecordingFakeMirror returns a fake mirror appending the paths of the
+// requests it receives to requested, unless it's nil.
+func newRecordingFakeMirror(t *testing.T, requested *[]string) *httptest.Server {
+	t.Helper()
+
+	markers := map[string]string{
+		"/release/stable.txt":                            "v1.30.2",
+		"/release/latest.txt":                            "v1.31.0-rc.1",
+		"/release/stable-1.30.txt":                       "v1.30.2",
+		"/release/stable-1.29.txt":                       "v1.29.7\n",
+		"/release/stable-1.28.txt":                       "v1.28.12",
+		"/release/latest-1.31.txt":                       "v1.31.0-rc.1",
+		"/release/latest-1.30.txt":                       "v1.30.2",
+		"/release/latest-1.29.txt":                       "v1.29.7",
+		"/release/latest-1.28.txt":                       "v1.28.13-rc.0",
+		"/release/v1.30.2/bin/linux/amd64/kubectl":       "",
+		"/release/v1.30.2/bin/windows/amd64/kubectl.exe": "",
+	}
+
+	var mu sync.Mutex
+	mirror := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requested != nil {
+			mu.Lock()
+			*requested = append(*requested, r.URL.Path)
+			mu.Unlock()
+		}
+		marker, found := markers[r.URL.Path]
+		if !found {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(marker)) //nolint: errcheck // the test fails anyway
+	}))
+	t.Cleanup(mirror.Close)
+
+	return mirror
+}
+
+func TestResolveVersion(t *testing.T) {
+	d := Downloder{KubeMirrorURL: newFakeMirror(t).URL}
+
+	tests := map[string]string{
+		"1.28.4":        "1.28.4",
+		"v1.31.0-rc.1":  "1.31.0-rc.1",
+		"1.29":          "1.29.7",
+		"v1.29":         "1.29.7",
+		"stable":        "1.30.2",
+		"latest":        "1.31.0-rc.1",
+		"stable-1.28":   "1.28.12",
+		">=1.27 <1.29":  "1.28.12",
+		"<1.28.5":       "1.28.4",
+		"1.29.x":        "1.29.7",
+		">1.29.7 <1.30": "",
+	}
+
+	for selector, expected := range tests {
+		version, err := d.ResolveVersion(selector)
+		if expected == "" {
+			if err == nil {
+				t.Errorf("selector %q: expected an error, got %s", selector, version)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("selector %q: unexpected error %v", selector, err)
+			continue
+		}
+		if version.String() != expected {
+			t.Errorf("selector %q: expected %s, got %s", selector, expected, version)
+		}
+	}
+}
+
+func TestResolveVersionMissingMarker(t *testing.T) {
+	d := Downloder{KubeMirrorURL: newFakeMirror(t).URL}
+
+	_, err := d.ResolveVersion("1.20")
+	if err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("expected a not found error, got %v", err)
+	}
+
+	if _, err = d.ResolveVersion("not a version"); err == nil {
+		t.Error("expected an error")
+	}
+}
+
+func TestLatestVersionInRangeWalksOnlyItsMinors(t *testing.T) {
+	tests := []struct {
+		versionRange string
+		expected     string
+		requested    string
+	}{
+		{
+			// the 1.30 pre-releases are inside of the range
+			versionRange: ">=1.29 <1.30",
+			expected:     "1.29.7",
+			requested:    "/release/stable.txt /release/stable-1.30.txt /release/stable-1.29.txt",
+		},
+		{
+			versionRange: "1.29",
+			expected:     "1.29.7",
+			requested:    "/release/stable.txt /release/stable-1.29.txt",
+		},
+		{
+			versionRange: "<=1.30.1",
+			expected:     "1.30.1",
+			requested:    "/release/stable.txt /release/stable-1.30.txt",
+		},
+		{
+			versionRange: "1.28 || >=1.31",
+			expected:     "1.28.12",
+			requested:    "/release/stable.txt /release/stable-1.31.txt /release/stable-1.30.txt /release/stable-1.29.txt /release/stable-1.28.txt",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.versionRange, func(t *testing.T) {
+			requested := []string{}
+			d := Downloder{KubeMirrorURL: newRecordingFakeMirror(t, &requested).URL}
+			versionRange, err := common.ParseVersionRange(tt.versionRange)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			version, err := d.LatestVersionInRange(versionRange)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if version.String() != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, version)
+			}
+			if actual := strings.Join(requested, " "); actual != tt.requested {
+				t.Errorf("expected requests %q, got %q", tt.requested, actual)
+			}
+		})
+	}
+}
+
+func TestLatestVersionInRangeAboveStable(t *testing.T) {
+	requested := []string{}
+	d := Downloder{KubeMirrorURL: newRecordingFakeMirror(t, &requested).URL}
+	versionRange, err := common.ParseVersionRange(">=1.33")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = d.LatestVersionInRange(versionRange); !errors.Is(err, errNoMinorVersionInRange) {
+		t.Errorf("expected errNoMinorVersionInRange, got %v", err)
+	}
+	if actual := strings.Join(requested, " "); actual != "/release/stable.txt" {
+		t.Errorf("only the stable release should be requested, got %q", actual)
+	}
+}
+
+func versionStrings(versions []semver.Version) string {
+	s := []string{}
+	for _, v := range versions {
+		s = append(s, v.String())
+	}
+	return strings.Join(s, " ")
+}
+
+func TestLatestReleases(t *testing.T) {
+	d := Downloder{KubeMirrorURL: newFakeMirror(t).URL}
+
+	releases, err := d.LatestReleases(0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := "1.31.0-rc.1 1.30.2 1.29.7 1.28.13-rc.0"
+	if actual := versionStrings(releases); actual != expected {
+		t.Errorf("expected %q, got %q", expected, actual)
+	}
+
+	releases, err = d.LatestReleases(2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected = "1.31.0-rc.1 1.30.2"
+	if actual := versionStrings(releases); actual != expected {
+		t.Errorf("expected %q, got %q", expected, actual)
+	}
+}
+
+func TestMinorReleases(t *testing.T) {
+	d := Downloder{KubeMirrorURL: newFakeMirror(t).URL}
+
+	releases, err := d.MinorReleases(1, 30)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if actual := versionStrings(releases); actual != "1.30.2 1.30.1 1.30.0" {
+		t.Errorf("unexpected releases %q", actual)
+	}
+
+	releases, err = d.MinorReleases(1, 31)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if actual := versionStrings(releases); actual != "1.31.0-rc.1" {
+		t.Errorf("unexpected releases %q", actual)
+	}
+
+	if _, err = d.MinorReleases(1, 20); !isNotFound(err) {
+		t.Errorf("expected a not found error, got %v", err)
+	}
+}
+
+func TestKubectlAvailable(t *testing.T) {
+	d := Downloder{KubeMirrorURL: newFakeMirror(t).URL}
+
+	tests := []struct {
+		version   string
+		platform  Platform
+		available bool
+	}{
+		{version: "1.30.2", platform: Platform{OS: "linux", Arch: "amd64"}, available: true},
+		{version: "1.30.2", platform: Platform{OS: "windows", Arch: "amd64"}, available: true},
+		{version: "1.30.2", platform: Platform{OS: "linux", Arch: "s390x"}, available: false},
+		{version: "1.29.7", platform: Platform{OS: "linux", Arch: "amd64"}, available: false},
+	}
+
+	for _, tt := range tests {
+		available, err := d.KubectlAvailable(semver.MustParse(tt.version), tt.platform)
+		if err != nil {
+			t.Errorf("%s %s: unexpected error %v", tt.version, tt.platform, err)
+			continue
+		}
+		if available != tt.available {
+			t.Errorf("%s %s: expected available %v, got %v", tt.version, tt.platform, tt.available, available)
+		}
+	}
+}
+
+func TestParsePlatform(t *testing.T) {
+	platform, err := ParsePlatform("darwin/arm64")
+	if err != nil || platform != (Platform{OS: "darwin", Arch: "arm64"}) {
+		t.Errorf("unexpected platform %v, error %v", platform, err)
+	}
+
+	for _, s := range []string{"linux", "linux/", "/amd64", "linux/amd64/v2"} {
+		if _, err = ParsePlatform(s); err == nil {
+			t.Errorf("%q: expected an error", s)
+		}
+	}
+}
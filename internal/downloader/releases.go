@@ -0,0 +1,178 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime"
+	"strings"
+
+	"github.com/blang/semver/v4"
+)
+
+// Platform identifies the operating system and the architecture kubectl
+// binaries are built for.
+type Platform struct {
+	OS   string
+	Arch string
+}
+
+// HostPlatform returns the platform kuberlr is running on.
+func HostPlatform() Platform {
+	return Platform{OS: runtime.GOOS, Arch: runtime.GOARCH}
+}
+
+// ParsePlatform parses a platform written as `<os>/<arch>`, like
+// `linux/amd64`.
+func ParsePlatform(s string) (Platform, error) {
+	goos, goarch, found := strings.Cut(s, "/")
+	if !found || goos == "" || goarch == "" || strings.Contains(goarch, "/") {
+		return Platform{}, fmt.Errorf("invalid platform %q, it must be written as <os>/<arch>", s)
+	}
+
+	return Platform{OS: goos, Arch: goarch}, nil
+}
+
+func (p Platform) String() string {
+	return p.OS + "/" + p.Arch
+}
+
+func (p Platform) binaryExt() string {
+	if p.OS == "windows" {
+		return ".exe"
+	}
+	return ""
+}
+
+// LatestReleases returns the latest release of each minor version, most
+// recent first. The minor version following the latest stable release is
+// included when it has pre-releases. At most maxMinors minor versions are
+// looked for, zero means all of them.
+func (d *Downloder) LatestReleases(maxMinors int) ([]semver.Version, error) {
+	releases := []semver.Version{}
+
+	err := d.walkMinorVersions(nil, nil, func(major, minor uint64) (bool, error) {
+		minorReleases, err := d.MinorReleases(major, minor)
+		if err != nil {
+			return false, err
+		}
+		releases = append(releases, minorReleases[0])
+		return maxMinors == 0 || len(releases) < maxMinors, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return releases, nil
+}
+
+// errNoMinorVersionInRange is returned when a range of versions doesn't
+// include any of the minor versions published on the mirror.
+var errNoMinorVersionInRange = errors.New("no minor version published on the mirror is inside of the requested range")
+
+// walkMinorVersions calls visit with each minor version of the major version
+// of the latest stable release, most recent first, until visit returns
+// false. The walk starts from the minor version following the latest stable
+// release: it can be released, or have pre-releases, while stable.txt still
+// points to the previous one. A not found error returned by visit skips that
+// minor version when it's the following one, otherwise it ends the walk
+// because no older release is available.
+// Only the minor versions between lowest and highest are visited, nil
+// bounds do not restrict the walk. An error is returned when no minor
+// version is inside of the bounds.
+func (d *Downloder) walkMinorVersions(lowest, highest *semver.Version, visit func(major, minor uint64) (bool, error)) error {
+	stable, err := d.UpstreamStableVersion()
+	if err != nil {
+		return err
+	}
+
+	first, last := stable.Minor+1, uint64(0)
+	if highest != nil && highest.Major == stable.Major && highest.Minor < first {
+		first = highest.Minor
+	}
+	if lowest != nil && lowest.Major == stable.Major {
+		last = lowest.Minor
+	}
+	if highest != nil && highest.Major < stable.Major ||
+		lowest != nil && lowest.Major > stable.Major ||
+		last > first {
+		return fmt.Errorf("%w: the latest stable release is %s", errNoMinorVersionInRange, stable)
+	}
+
+	for minor := first; ; minor-- {
+		more, err := visit(stable.Major, minor)
+		switch {
+		case isNotFound(err) && minor > stable.Minor:
+			continue
+		case isNotFound(err):
+			return nil
+		case err != nil:
+			return err
+		}
+
+		if !more || minor == last {
+			return nil
+		}
+	}
+}
+
+// MinorReleases returns all the releases of the given minor version, most
+// recent first. The latest pre-release of the minor version is returned
+// too, when it's more recent than its latest stable release. All the patch
+// releases up to the latest one are assumed to exist.
+func (d *Downloder) MinorReleases(major, minor uint64) ([]semver.Version, error) {
+	releases := []semver.Version{}
+
+	latest, err := d.markerVersion(fmt.Sprintf("latest-%d.%d", major, minor))
+	if err != nil {
+		return nil, err
+	}
+
+	stable, err := d.markerVersion(fmt.Sprintf("stable-%d.%d", major, minor))
+	switch {
+	case isNotFound(err):
+		// only pre-releases of this minor version have been published
+		return append(releases, latest), nil
+	case err != nil:
+		return nil, err
+	}
+
+	if latest.GT(stable) {
+		releases = append(releases, latest)
+	}
+	for patch := stable.Patch + 1; patch > 0; patch-- {
+		releases = append(releases, semver.Version{Major: stable.Major, Minor: stable.Minor, Patch: patch - 1})
+	}
+
+	return releases, nil
+}
+
+// KubectlAvailable returns true when the mirror provides the kubectl binary
+// of the given version for the given platform. The binary is not downloaded.
+func (d *Downloder) KubectlAvailable(version semver.Version, platform Platform) (bool, error) {
+	downloadURL, err := d.kubectlPlatformDownloadURL(version, platform)
+	if err != nil {
+		return false, err
+	}
+
+	req, err := http.NewRequestWithContext(context.TODO(), http.MethodHead, downloadURL, nil)
+	if err != nil {
+		return false, err
+	}
+	res, err := (&http.Client{Timeout: d.Timeout}).Do(req)
+	if err != nil {
+		return false, err
+	}
+	res.Body.Close()
+
+	switch res.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound, http.StatusForbidden:
+		// some object storages answer with forbidden to missing objects
+		return false, nil
+	default:
+		return false, &httpStatusError{url: downloadURL, status: res.Status, statusCode: res.StatusCode}
+	}
+}
@@ -0,0 +1,116 @@
+package downloader
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/blang/semver/v4"
+
+	"github.com/flavio/kuberlr/internal/common"
+)
+
+const (
+	// ReleasesCacheFileName is the name of the file where kuberlr caches
+	// the releases published on the mirrors.
+	ReleasesCacheFileName = "remote-releases.json"
+
+	// DefaultReleasesCacheTTL is how long the releases published on a
+	// mirror are considered fresh.
+	DefaultReleasesCacheTTL = time.Hour
+)
+
+// releasesCacheEntry is a list of releases fetched from a mirror.
+type releasesCacheEntry struct {
+	Versions  []string  `json:"versions"`
+	FetchedAt time.Time `json:"fetchedAt"`
+}
+
+// CachedAt returns when the releases have been fetched.
+func (e releasesCacheEntry) CachedAt() time.Time {
+	return e.FetchedAt
+}
+
+// ReleasesCache keeps on disk the releases published on the mirrors, keyed
+// by mirror URL and minor version. Fresh entries are used instead of
+// contacting the mirror, expired ones are used only when the mirror cannot
+// be reached. The cache can be used by multiple goroutines at the same time.
+type ReleasesCache struct {
+	cache *common.JSONCache[releasesCacheEntry]
+}
+
+// DefaultReleasesCachePath returns the path to the file used to cache the
+// releases published on the mirrors.
+func DefaultReleasesCachePath() string {
+	return filepath.Join(common.KuberlrDir(), ReleasesCacheFileName)
+}
+
+// LoadReleasesCache reads the cache stored at the given path. A missing
+// file results in an empty cache. Entries older than ttl are considered
+// expired. A usable, empty, cache is returned together with the error when
+// the file cannot be read.
+func LoadReleasesCache(path string, ttl time.Duration) (*ReleasesCache, error) {
+	cache, err := common.LoadJSONCache[releasesCacheEntry](path, ttl)
+	return &ReleasesCache{cache: cache}, err
+}
+
+// LatestReleases returns the same releases as Downloder.LatestReleases,
+// contacting the mirror only when the cached ones are expired.
+func (c *ReleasesCache) LatestReleases(d *Downloder, maxMinors int) ([]semver.Version, error) {
+	return c.releases(d, fmt.Sprintf("latest-%d", maxMinors), func() ([]semver.Version, error) {
+		return d.LatestReleases(maxMinors)
+	})
+}
+
+// MinorReleases returns the same releases as Downloder.MinorReleases,
+// contacting the mirror only when the cached ones are expired.
+func (c *ReleasesCache) MinorReleases(d *Downloder, major, minor uint64) ([]semver.Version, error) {
+	return c.releases(d, fmt.Sprintf("%d.%d", major, minor), func() ([]semver.Version, error) {
+		return d.MinorReleases(major, minor)
+	})
+}
+
+func (c *ReleasesCache) releases(d *Downloder, list string, fetch func() ([]semver.Version, error)) ([]semver.Version, error) {
+	mirror, err := d.getKubeMirrorURL()
+	if err != nil {
+		return nil, err
+	}
+	key := mirror + "#" + list
+
+	entry, found, fresh := c.cache.Get(key)
+	if fresh {
+		if versions, parseErr := parseVersions(entry.Versions); parseErr == nil {
+			return versions, nil
+		}
+	}
+
+	versions, err := fetch()
+	if err != nil {
+		if found {
+			if stale, parseErr := parseVersions(entry.Versions); parseErr == nil {
+				return stale, nil
+			}
+		}
+		return nil, err
+	}
+
+	entry = releasesCacheEntry{FetchedAt: time.Now()}
+	for _, v := range versions {
+		entry.Versions = append(entry.Versions, v.String())
+	}
+
+	return versions, c.cache.Put(key, entry)
+}
+
+func parseVersions(versions []string) ([]semver.Version, error) {
+	parsed := make([]semver.Version, 0, len(versions))
+	for _, v := range versions {
+		version, err := semver.Parse(v)
+		if err != nil {
+			return nil, err
+		}
+		parsed = append(parsed, version)
+	}
+
+	return parsed, nil
+}
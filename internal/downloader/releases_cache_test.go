@@ -0,0 +1,76 @@
+package downloader
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestReleasesCache(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ReleasesCacheFileName)
+	mirror := newFakeMirror(t)
+	d := Downloder{KubeMirrorURL: mirror.URL}
+
+	cache, err := LoadReleasesCache(path, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	releases, err := cache.LatestReleases(&d, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if actual := versionStrings(releases); actual != "1.31.0-rc.1 1.30.2 1.29.7 1.28.13-rc.0" {
+		t.Errorf("unexpected releases %q", actual)
+	}
+	if _, err = cache.MinorReleases(&d, 1, 30); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// from now on the releases can only come from the cache
+	mirror.Close()
+
+	cache, err = LoadReleasesCache(path, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	releases, err = cache.MinorReleases(&d, 1, 30)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if actual := versionStrings(releases); actual != "1.30.2 1.30.1 1.30.0" {
+		t.Errorf("unexpected releases %q", actual)
+	}
+
+	// expired entries are used when the mirror cannot be reached
+	cache, err = LoadReleasesCache(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	releases, err = cache.LatestReleases(&d, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if actual := versionStrings(releases); actual != "1.31.0-rc.1 1.30.2 1.29.7 1.28.13-rc.0" {
+		t.Errorf("unexpected stale releases %q", actual)
+	}
+
+	if _, err = cache.MinorReleases(&d, 1, 29); err == nil {
+		t.Error("expected an error for releases never fetched")
+	}
+}
+
+func TestLoadReleasesCacheCorrupted(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ReleasesCacheFileName)
+	if err := os.WriteFile(path, []byte("{"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cache, err := LoadReleasesCache(path, time.Hour)
+	if err == nil {
+		t.Error("expected an error")
+	}
+	if cache == nil || len(cache.cache.Entries()) != 0 {
+		t.Errorf("expected a usable, empty, cache")
+	}
+}
@@ -2,14 +2,33 @@ package finder
 
 import (
 	"sort"
+	"time"
 
 	"github.com/blang/semver/v4"
 )
 
+const (
+	// OriginLocal is the origin of the kubectl binaries available only to
+	// the user running kuberlr.
+	OriginLocal = "local"
+	// OriginSystem is the origin of the kubectl binaries available to all
+	// the users of the system.
+	OriginSystem = "system"
+)
+
 // KubectlBinary describes a kubectl binary.
 type KubectlBinary struct {
 	Path    string
 	Version semver.Version
+	// Origin is either OriginLocal or OriginSystem
+	Origin string
+	// Dir is the directory where the binary has been found
+	Dir string
+	// RealPath is the path of the binary once all the symlinks are resolved
+	RealPath string
+	// LastUsed is when kuberlr used the binary for the last time, it's
+	// the zero value when the binary has never been used
+	LastUsed time.Time
 }
 
 // KubectlBinaries is a list of KubectlBinary objects.
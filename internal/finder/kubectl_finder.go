@@ -5,45 +5,128 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/flavio/kuberlr/internal/osexec"
 
 	"github.com/flavio/kuberlr/internal/common"
 
 	"github.com/blang/semver/v4"
+	"k8s.io/klog"
 )
 
 // KubectlFinder holds data about where to look the kubectl binaries.
 type KubectlFinder struct {
 	localBinaryPath string
-	sysBinaryPath   string
+	sysBinaryPaths  []string
+	usagePath       string
+	// selfPath is the real path of the kuberlr binary, which must never
+	// be handled as a kubectl binary
+	selfPath string
+	// probe, when set, is used to detect the version of the binaries by
+	// inspecting them
+	probe *versionProbe
 }
 
 // NewKubectlFinder returns a properly initialized KubectlFinder object.
-func NewKubectlFinder(local, sys string) *KubectlFinder {
+// The system-wide binaries are looked for inside of the `sys` directories,
+// in the given order.
+func NewKubectlFinder(local string, sys []string) *KubectlFinder {
 	if local == "" {
 		local = common.LocalDownloadDir()
 	}
-	if sys == "" {
-		sys = common.SystemPath
+	if len(sys) == 0 {
+		sys = []string{common.SystemPath}
+	}
+
+	var selfPath string
+	if exe, err := os.Executable(); err == nil {
+		if selfPath, err = filepath.EvalSymlinks(exe); err != nil {
+			selfPath = exe
+		}
 	}
 
 	return &KubectlFinder{
 		localBinaryPath: local,
-		sysBinaryPath:   sys,
+		sysBinaryPaths:  sys,
+		usagePath:       DefaultUsagePath(),
+		selfPath:        selfPath,
+		probe:           newVersionProbe(DefaultVersionCachePath()),
 	}
 }
 
 // SystemKubectlBinaries returns the list of kubectl binaries that are
-// available to all the users of the system.
+// available to all the users of the system. The binaries found inside
+// of the readable directories are returned even when some directories
+// cannot be read.
 func (f *KubectlFinder) SystemKubectlBinaries() (KubectlBinaries, error) {
-	return findKubectlBinaries(f.sysBinaryPath)
+	var bins KubectlBinaries
+	var errs []error
+
+	for _, dir := range f.sysBinaryPaths {
+		dirBins, err := f.findKubectlBinaries(dir, OriginSystem)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		bins = append(bins, dirBins...)
+	}
+	bins = uniqueKubectlBinaries(bins)
+	f.setLastUsed(bins)
+
+	return bins, errors.Join(errs...)
 }
 
 // LocalKubectlBinaries returns the list of kubectl binaries that are
 // available only to the user currently running kuberlr.
 func (f *KubectlFinder) LocalKubectlBinaries() (KubectlBinaries, error) {
-	return findKubectlBinaries(f.localBinaryPath)
+	bins, err := f.findKubectlBinaries(f.localBinaryPath, OriginLocal)
+	if err != nil {
+		return bins, err
+	}
+	f.setLastUsed(bins)
+
+	return bins, nil
+}
+
+// SystemPaths returns the directories where the system-wide kubectl
+// binaries are looked for.
+func (f *KubectlFinder) SystemPaths() []string {
+	return f.sysBinaryPaths
+}
+
+// RemoveKubectlBinaries deletes the given binaries, together with the
+// usage information and the detected versions kuberlr keeps about them.
+func (f *KubectlFinder) RemoveKubectlBinaries(bins KubectlBinaries) error {
+	err := RemoveKubectlBinaries(bins, f.usagePath)
+
+	if f.probe != nil {
+		for _, b := range bins {
+			if _, statErr := os.Lstat(b.Path); os.IsNotExist(statErr) {
+				f.probe.Forget(b.Path)
+			}
+		}
+		f.probe.Save()
+	}
+
+	return err
+}
+
+// setLastUsed fills the LastUsed attribute of the given binaries.
+func (f *KubectlFinder) setLastUsed(bins KubectlBinaries) {
+	if f.usagePath == "" || len(bins) == 0 {
+		return
+	}
+
+	usage, err := LoadUsage(f.usagePath)
+	if err != nil {
+		klog.V(common.VerbosityOne).Infof("cannot read kubectl usage from %s: %v", f.usagePath, err)
+		return
+	}
+
+	for i := range bins {
+		bins[i].LastUsed = usage.LastUsed[bins[i].Path]
+	}
 }
 
 // AllKubectlBinaries returns all the kubectl binaries available to the
@@ -57,44 +140,54 @@ func (f *KubectlFinder) AllKubectlBinaries(reverseSort bool) KubectlBinaries {
 	}
 
 	systemBin, err := f.SystemKubectlBinaries()
-	if err == nil {
-		bins = append(bins, systemBin...)
+	if err != nil {
+		klog.V(common.VerbosityOne).Infof("cannot look for system-wide kubectl binaries: %v", err)
 	}
+	bins = append(bins, systemBin...)
 
+	// the same binary can be reachable from different directories
+	bins = uniqueKubectlBinaries(bins)
 	SortKubectlByVersion(bins, reverseSort)
 
 	return bins
 }
 
+// HasKubectlNamingScheme returns true when the version of the kubectl binary
+// can be inferred from its name, without inspecting it.
+func HasKubectlNamingScheme(filename string) bool {
+	if _, err := inferLocalKubectlVersion(filename); err == nil {
+		return true
+	}
+	_, err := inferSystemKubectlVersion(filename)
+	return err == nil
+}
+
 func inferLocalKubectlVersion(filename string) (semver.Version, error) {
-	var major, minor, patch uint64
-	numScans, err := fmt.Sscanf(
-		osexec.TrimExt(filename),
-		common.KubectlLocalNamingScheme,
-		&major,
-		&minor,
-		&patch)
+	// the name is `kubectl` followed by the full version, including the
+	// pre-release identifiers (e.g.: `kubectl1.31.0-rc.1`)
+	name := osexec.TrimExt(filename)
+	if !strings.HasPrefix(name, "kubectl") {
+		return semver.Version{}, errors.New("not parsable")
+	}
 
-	if numScans == 3 && err == nil {
-		sv := semver.Version{
-			Major: major,
-			Minor: minor,
-			Patch: patch,
-		}
-		return sv, nil
+	sv, err := semver.Parse(strings.TrimPrefix(name, "kubectl"))
+	if err != nil || len(sv.Build) > 0 {
+		return semver.Version{}, errors.New("not parsable")
 	}
-	return semver.Version{}, errors.New("not parsable")
+	return sv, nil
 }
 
 func inferSystemKubectlVersion(filename string) (semver.Version, error) {
 	var major, minor uint64
+	name := osexec.TrimExt(filename)
 	numScans, err := fmt.Sscanf(
-		osexec.TrimExt(filename),
+		name,
 		common.KubectlSystemNamingScheme,
 		&major,
 		&minor)
 
-	if numScans == 2 && err == nil {
+	// Sscanf ignores trailing characters, like the ones of `kubectl1.31-rc.1`
+	if numScans == 2 && err == nil && fmt.Sprintf(common.KubectlSystemNamingScheme, major, minor) == name {
 		sv := semver.Version{
 			Major: major,
 			Minor: minor,
@@ -105,7 +198,7 @@ func inferSystemKubectlVersion(filename string) (semver.Version, error) {
 	return semver.Version{}, errors.New("not parsable")
 }
 
-func findKubectlBinaries(path string) (KubectlBinaries, error) {
+func (f *KubectlFinder) findKubectlBinaries(path, origin string) (KubectlBinaries, error) {
 	var binaries KubectlBinaries
 
 	kubectlBins, err := os.ReadDir(path)
@@ -117,23 +210,126 @@ func findKubectlBinaries(path string) (KubectlBinaries, error) {
 	}
 
 	for _, file := range kubectlBins {
-		var version semver.Version
-		var internalErr error
+		if file.IsDir() {
+			continue
+		}
+
+		nameVersion, nameErr := inferLocalKubectlVersion(file.Name())
+		if nameErr != nil {
+			nameVersion, nameErr = inferSystemKubectlVersion(file.Name())
+		}
+		if nameErr != nil && (f.probe == nil || !LooksLikeKubectl(file.Name())) {
+			continue
+		}
 
-		version, internalErr = inferLocalKubectlVersion(file.Name())
+		binPath := filepath.Join(path, file.Name())
+		realPath, internalErr := filepath.EvalSymlinks(binPath)
 		if internalErr != nil {
-			version, internalErr = inferSystemKubectlVersion(file.Name())
-			if internalErr != nil {
-				continue
-			}
+			klog.V(common.VerbosityTwo).Infof("ignoring %s: %v", binPath, internalErr)
+			continue
+		}
+		if f.selfPath != "" && realPath == f.selfPath {
+			// this is a link pointing to kuberlr itself, using it would
+			// lead to an endless loop
+			continue
+		}
+
+		version, found := f.detectVersion(binPath, nameVersion, nameErr == nil)
+		if !found {
+			continue
 		}
 
 		bin := KubectlBinary{
-			Path:    filepath.Join(path, file.Name()),
-			Version: version,
+			Path:     binPath,
+			Version:  version,
+			Origin:   origin,
+			Dir:      path,
+			RealPath: realPath,
 		}
 		binaries = append(binaries, bin)
 	}
+	if f.probe != nil {
+		f.probe.Save()
+	}
 
 	return binaries, nil
 }
+
+// detectVersion returns the version of the given kubectl binary. The
+// version detected by inspecting the binary takes precedence over the
+// one inferred from its name. The boolean is false when the version
+// is unknown.
+func (f *KubectlFinder) detectVersion(binPath string, nameVersion semver.Version, nameValid bool) (semver.Version, bool) {
+	if f.probe == nil {
+		return nameVersion, nameValid
+	}
+
+	info, err := os.Stat(binPath)
+	if err != nil {
+		return nameVersion, nameValid
+	}
+
+	version, found := f.probe.Version(binPath, info)
+	if !found {
+		return nameVersion, nameValid
+	}
+	if nameValid && !sameKubectlVersion(nameVersion, version) {
+		klog.V(common.VerbosityOne).Infof(
+			"%s is named after version %s, but it is kubectl %s",
+			binPath, nameVersion, version)
+	}
+
+	return version, true
+}
+
+// sameKubectlVersion returns true when the version inferred from the name
+// of a binary matches the detected one. System-wide binaries are named
+// without the patch level, which is then ignored.
+func sameKubectlVersion(nameVersion, detected semver.Version) bool {
+	if nameVersion.EQ(detected) {
+		return true
+	}
+
+	return nameVersion.Patch == 0 &&
+		nameVersion.Major == detected.Major &&
+		nameVersion.Minor == detected.Minor
+}
+
+// uniqueKubectlBinaries removes the binaries that have the same real path
+// of a binary that precedes them.
+func uniqueKubectlBinaries(bins KubectlBinaries) KubectlBinaries {
+	seen := map[string]bool{}
+	unique := KubectlBinaries{}
+
+	for _, b := range bins {
+		key := b.RealPath
+		if key == "" {
+			key = b.Path
+		}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		unique = append(unique, b)
+	}
+
+	return unique
+}
+
+// KubectlVersion returns the version of the kubectl binary at the given
+// path, which can be outside of the directories handled by the finder. The
+// boolean is false when the version cannot be detected.
+func (f *KubectlFinder) KubectlVersion(path string) (semver.Version, bool) {
+	name := filepath.Base(path)
+	nameVersion, nameErr := inferLocalKubectlVersion(name)
+	if nameErr != nil {
+		nameVersion, nameErr = inferSystemKubectlVersion(name)
+	}
+
+	version, found := f.detectVersion(path, nameVersion, nameErr == nil)
+	if f.probe != nil {
+		f.probe.Save()
+	}
+
+	return version, found
+}
@@ -3,6 +3,7 @@ package finder
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -31,7 +32,7 @@ func setupFilesystemTest() (localCacheTestData, error) {
 		FakeSysBinPath: fakeSysBin,
 		Finder: KubectlFinder{
 			localBinaryPath: fakeHome,
-			sysBinaryPath:   fakeSysBin,
+			sysBinaryPaths:  []string{fakeSysBin},
 		},
 	}
 	return td, nil
@@ -109,3 +110,76 @@ func TestLocalKubectlVersionsDownloadDirNotCreated(t *testing.T) {
 	require.NoError(t, err)
 	assert.Empty(t, bins)
 }
+
+func TestSystemKubectlBinariesMultipleDirectories(t *testing.T) {
+	td, err := setupFilesystemTest()
+	require.NoError(t, err)
+	defer func() {
+		if err = teardownFilesystemTest(td); err != nil {
+			panic(fmt.Sprintf("Error while tearing down test filesystem: %v\n", err))
+		}
+	}()
+
+	otherSysBinPath := filepath.Join(td.FakeSysBinPath, "other")
+	kuberlrPath := filepath.Join(td.FakeSysBinPath, "kuberlr")
+	td.Finder.sysBinaryPaths = append(td.Finder.sysBinaryPaths, otherSysBinPath)
+	td.Finder.selfPath = kuberlrPath
+
+	systemBins := fakeKubectlBinaries(
+		td.FakeSysBinPath,
+		[]string{"1.28.0"},
+		&systemKubectlNamer{})
+	require.NoError(t, createFakeKubectlBinaries(systemBins))
+
+	otherSystemBins := fakeKubectlBinaries(
+		otherSysBinPath,
+		[]string{"1.29.0"},
+		&systemKubectlNamer{})
+	require.NoError(t, createFakeKubectlBinaries(otherSystemBins))
+
+	// the same binary reachable via a symlink is reported only once
+	require.NoError(t, os.Symlink(systemBins[0].Path, filepath.Join(otherSysBinPath, "kubectl1.28")))
+
+	// links pointing to kuberlr itself are ignored
+	require.NoError(t, os.WriteFile(kuberlrPath, []byte{}, 0o600))
+	require.NoError(t, os.Symlink(kuberlrPath, filepath.Join(otherSysBinPath, "kubectl1.30")))
+
+	actual, err := td.Finder.SystemKubectlBinaries()
+	require.NoError(t, err)
+
+	//nolint: gocritic // append returns a different array on purpose, we're joining two arrays
+	expected := append(systemBins, otherSystemBins...)
+	assert.Equal(t, expected, actual)
+}
+
+func TestInferKubectlVersionFromName(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{name: "kubectl1.28.4", expected: "1.28.4"},
+		{name: "kubectl1.31.0-rc.1", expected: "1.31.0-rc.1"},
+		{name: "kubectl1.31.0-alpha.2", expected: "1.31.0-alpha.2"},
+		{name: "kubectl1.28", expected: "1.28.0"},
+		{name: "kubectl1.31-rc.1", expected: ""},
+		{name: "kubectl1.28.4+k3s1", expected: ""},
+		{name: "kubectl", expected: ""},
+		{name: "kubectl-krew", expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			version, err := inferLocalKubectlVersion(tt.name)
+			if err != nil {
+				version, err = inferSystemKubectlVersion(tt.name)
+			}
+
+			if tt.expected == "" {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, version.String())
+		})
+	}
+}
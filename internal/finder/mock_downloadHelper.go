@@ -4,6 +4,7 @@ package finder
 
 import (
 	semver "github.com/blang/semver/v4"
+	common "github.com/flavio/kuberlr/internal/common"
 	mock "github.com/stretchr/testify/mock"
 )
 
@@ -67,6 +68,62 @@ func (_c *MockdownloadHelper_GetKubectlBinary_Call) RunAndReturn(run func(semver
 	return _c
 }
 
+// LatestVersionInRange provides a mock function with given fields: versionRange
+func (_m *MockdownloadHelper) LatestVersionInRange(versionRange *common.VersionRange) (semver.Version, error) {
+	ret := _m.Called(versionRange)
+
+	if len(ret) == 0 {
+		panic("no return value specified for LatestVersionInRange")
+	}
+
+	var r0 semver.Version
+	var r1 error
+	if rf, ok := ret.Get(0).(func(*common.VersionRange) (semver.Version, error)); ok {
+		return rf(versionRange)
+	}
+	if rf, ok := ret.Get(0).(func(*common.VersionRange) semver.Version); ok {
+		r0 = rf(versionRange)
+	} else {
+		r0 = ret.Get(0).(semver.Version)
+	}
+
+	if rf, ok := ret.Get(1).(func(*common.VersionRange) error); ok {
+		r1 = rf(versionRange)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockdownloadHelper_LatestVersionInRange_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'LatestVersionInRange'
+type MockdownloadHelper_LatestVersionInRange_Call struct {
+	*mock.Call
+}
+
+// LatestVersionInRange is a helper method to define mock.On call
+//   - versionRange *common.VersionRange
+func (_e *MockdownloadHelper_Expecter) LatestVersionInRange(versionRange interface{}) *MockdownloadHelper_LatestVersionInRange_Call {
+	return &MockdownloadHelper_LatestVersionInRange_Call{Call: _e.mock.On("LatestVersionInRange", versionRange)}
+}
+
+func (_c *MockdownloadHelper_LatestVersionInRange_Call) Run(run func(versionRange *common.VersionRange)) *MockdownloadHelper_LatestVersionInRange_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(*common.VersionRange))
+	})
+	return _c
+}
+
+func (_c *MockdownloadHelper_LatestVersionInRange_Call) Return(_a0 semver.Version, _a1 error) *MockdownloadHelper_LatestVersionInRange_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockdownloadHelper_LatestVersionInRange_Call) RunAndReturn(run func(*common.VersionRange) (semver.Version, error)) *MockdownloadHelper_LatestVersionInRange_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // UpstreamStableVersion provides a mock function with given fields:
 func (_m *MockdownloadHelper) UpstreamStableVersion() (semver.Version, error) {
 	ret := _m.Called()
@@ -65,6 +65,65 @@ func (_c *MockiFinder_AllKubectlBinaries_Call) RunAndReturn(run func(bool) Kubec
 	return _c
 }
 
+// EnforceMaxCacheSize provides a mock function with given fields: maxSize, keepPath
+func (_m *MockiFinder) EnforceMaxCacheSize(maxSize int64, keepPath string) (KubectlBinaries, error) {
+	ret := _m.Called(maxSize, keepPath)
+
+	if len(ret) == 0 {
+		panic("no return value specified for EnforceMaxCacheSize")
+	}
+
+	var r0 KubectlBinaries
+	var r1 error
+	if rf, ok := ret.Get(0).(func(int64, string) (KubectlBinaries, error)); ok {
+		return rf(maxSize, keepPath)
+	}
+	if rf, ok := ret.Get(0).(func(int64, string) KubectlBinaries); ok {
+		r0 = rf(maxSize, keepPath)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(KubectlBinaries)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(int64, string) error); ok {
+		r1 = rf(maxSize, keepPath)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockiFinder_EnforceMaxCacheSize_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'EnforceMaxCacheSize'
+type MockiFinder_EnforceMaxCacheSize_Call struct {
+	*mock.Call
+}
+
+// EnforceMaxCacheSize is a helper method to define mock.On call
+//   - maxSize int64
+//   - keepPath string
+func (_e *MockiFinder_Expecter) EnforceMaxCacheSize(maxSize interface{}, keepPath interface{}) *MockiFinder_EnforceMaxCacheSize_Call {
+	return &MockiFinder_EnforceMaxCacheSize_Call{Call: _e.mock.On("EnforceMaxCacheSize", maxSize, keepPath)}
+}
+
+func (_c *MockiFinder_EnforceMaxCacheSize_Call) Run(run func(maxSize int64, keepPath string)) *MockiFinder_EnforceMaxCacheSize_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(int64), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *MockiFinder_EnforceMaxCacheSize_Call) Return(_a0 KubectlBinaries, _a1 error) *MockiFinder_EnforceMaxCacheSize_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockiFinder_EnforceMaxCacheSize_Call) RunAndReturn(run func(int64, string) (KubectlBinaries, error)) *MockiFinder_EnforceMaxCacheSize_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // NewMockiFinder creates a new instance of MockiFinder. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewMockiFinder(t interface {
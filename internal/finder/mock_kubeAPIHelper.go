@@ -20,9 +20,9 @@ func (_m *MockkubeAPIHelper) EXPECT() *MockkubeAPIHelper_Expecter {
 	return &MockkubeAPIHelper_Expecter{mock: &_m.Mock}
 }
 
-// Version provides a mock function with given fields: timeout
-func (_m *MockkubeAPIHelper) Version(timeout int64) (semver.Version, error) {
-	ret := _m.Called(timeout)
+// Version provides a mock function with given fields: timeout, args
+func (_m *MockkubeAPIHelper) Version(timeout int64, args []string) (semver.Version, error) {
+	ret := _m.Called(timeout, args)
 
 	if len(ret) == 0 {
 		panic("no return value specified for Version")
@@ -30,17 +30,17 @@ func (_m *MockkubeAPIHelper) Version(timeout int64) (semver.Version, error) {
 
 	var r0 semver.Version
 	var r1 error
-	if rf, ok := ret.Get(0).(func(int64) (semver.Version, error)); ok {
-		return rf(timeout)
+	if rf, ok := ret.Get(0).(func(int64, []string) (semver.Version, error)); ok {
+		return rf(timeout, args)
 	}
-	if rf, ok := ret.Get(0).(func(int64) semver.Version); ok {
-		r0 = rf(timeout)
+	if rf, ok := ret.Get(0).(func(int64, []string) semver.Version); ok {
+		r0 = rf(timeout, args)
 	} else {
 		r0 = ret.Get(0).(semver.Version)
 	}
 
-	if rf, ok := ret.Get(1).(func(int64) error); ok {
-		r1 = rf(timeout)
+	if rf, ok := ret.Get(1).(func(int64, []string) error); ok {
+		r1 = rf(timeout, args)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -55,13 +55,14 @@ type MockkubeAPIHelper_Version_Call struct {
 
 // Version is a helper method to define mock.On call
 //   - timeout int64
-func (_e *MockkubeAPIHelper_Expecter) Version(timeout interface{}) *MockkubeAPIHelper_Version_Call {
-	return &MockkubeAPIHelper_Version_Call{Call: _e.mock.On("Version", timeout)}
+//   - args []string
+func (_e *MockkubeAPIHelper_Expecter) Version(timeout interface{}, args interface{}) *MockkubeAPIHelper_Version_Call {
+	return &MockkubeAPIHelper_Version_Call{Call: _e.mock.On("Version", timeout, args)}
 }
 
-func (_c *MockkubeAPIHelper_Version_Call) Run(run func(timeout int64)) *MockkubeAPIHelper_Version_Call {
+func (_c *MockkubeAPIHelper_Version_Call) Run(run func(timeout int64, args []string)) *MockkubeAPIHelper_Version_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(int64))
+		run(args[0].(int64), args[1].([]string))
 	})
 	return _c
 }
@@ -71,7 +72,7 @@ func (_c *MockkubeAPIHelper_Version_Call) Return(_a0 semver.Version, _a1 error)
 	return _c
 }
 
-func (_c *MockkubeAPIHelper_Version_Call) RunAndReturn(run func(int64) (semver.Version, error)) *MockkubeAPIHelper_Version_Call {
+func (_c *MockkubeAPIHelper_Version_Call) RunAndReturn(run func(int64, []string) (semver.Version, error)) *MockkubeAPIHelper_Version_Call {
 	_c.Call.Return(run)
 	return _c
 }
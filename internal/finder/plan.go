@@ -0,0 +1,264 @@
+package finder
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/blang/semver/v4"
+	"k8s.io/klog"
+
+	"github.com/flavio/kuberlr/internal/common"
+)
+
+const (
+	// ActionUse means an existing kubectl binary is used
+	ActionUse = "use"
+	// ActionDownload means a kubectl binary is downloaded from the upstream
+	// mirror
+	ActionDownload = "download"
+	// ActionUseLatest means the newest kubectl binary is used, even if it
+	// doesn't satisfy the request
+	ActionUseLatest = "use-latest"
+	// ActionFail means no kubectl binary can be used
+	ActionFail = "fail"
+)
+
+// CandidateDecision tells whether a kubectl binary available on the system
+// satisfies the request, and why.
+type CandidateDecision struct {
+	KubectlBinary
+	Accepted bool
+	Selected bool
+	// Score is the ranking score of the compatible binaries, it's nil when
+	// the binaries are not ranked
+	Score  *uint64
+	Reason string
+}
+
+// Plan is the choice of the kubectl binary made by the Versioner, together
+// with the decision taken for each binary available on the system. Nothing
+// is downloaded until the plan is carried out by Versioner.Execute.
+type Plan struct {
+	Candidates []CandidateDecision
+	Action     string
+	// Binary is the binary used by ActionUse and ActionUseLatest, or the
+	// one created by ActionDownload. The path and the version of the
+	// downloaded binary are empty when they are known only once the
+	// mirror has been contacted.
+	Binary KubectlBinary
+	// Fallback is the binary used when the download fails, it's set only
+	// when UseLatestIfNoCompatible is enabled
+	Fallback *KubectlBinary
+	Reason   string
+
+	// downloadVersion is the version downloaded by ActionDownload, unless
+	// downloadRange is set
+	downloadVersion semver.Version
+	// downloadRange makes ActionDownload download the most recent stable
+	// release inside of it
+	downloadRange *common.VersionRange
+	// err is returned by ActionFail
+	err error
+}
+
+// PlanCompatible plans the usage of the best kubectl binary compatible with
+// the given API server version, according to the skew policy and the
+// ranking preferences.
+func (v *Versioner) PlanCompatible(version semver.Version, allowDownload bool, useLatestIfNoCompatible bool) Plan {
+	bins := v.kFinder.AllKubectlBinaries(true)
+	policy := v.SkewPolicy()
+	compatibleRange := policy.RangeRule(version)
+
+	ranked := RankKubectlBinaries(version, bins, policy, v.RankingPreferences())
+	scores := map[string]uint64{}
+	for _, r := range ranked {
+		scores[r.Path] = r.Score
+	}
+
+	plan := Plan{}
+	for _, b := range bins {
+		decision := CandidateDecision{KubectlBinary: b}
+		if score, compatible := scores[b.Path]; compatible {
+			decision.Accepted = true
+			decision.Score = &score
+			decision.Reason = fmt.Sprintf("compatible with %s, score %d", version, score)
+		} else {
+			decision.Reason = "outside of the compatible range " + compatibleRange
+		}
+		plan.Candidates = append(plan.Candidates, decision)
+	}
+
+	if len(ranked) > 0 {
+		plan.use(ranked[0].KubectlBinary, "highest ranked compatible binary")
+		return plan
+	}
+
+	missing := &common.NoVersionFoundError{Range: compatibleRange}
+	plan.download(bins, allowDownload, useLatestIfNoCompatible, "no compatible binary found", missing)
+	if plan.Action == ActionDownload {
+		plan.downloadExactly(version)
+	}
+
+	return plan
+}
+
+// PlanExact plans the usage of a kubectl binary with exactly the given
+// version, like the one pinned by a context rule. When none is available,
+// that version is downloaded.
+func (v *Versioner) PlanExact(version semver.Version, allowDownload bool, useLatestIfNoCompatible bool) Plan {
+	bins := v.kFinder.AllKubectlBinaries(true)
+
+	plan := Plan{}
+	var selected *KubectlBinary
+	for _, b := range bins {
+		decision := CandidateDecision{KubectlBinary: b, Reason: "not the requested version " + version.String()}
+		if b.Version.EQ(version) {
+			decision.Accepted = true
+			decision.Reason = "the requested version"
+			if selected == nil {
+				selected = &b
+			}
+		}
+		plan.Candidates = append(plan.Candidates, decision)
+	}
+
+	if selected != nil {
+		plan.use(*selected, "binary with the requested version")
+		return plan
+	}
+
+	plan.download(bins, allowDownload, useLatestIfNoCompatible, "no binary with the requested version found",
+		fmt.Errorf("kubectl %s is not available", version))
+	if plan.Action == ActionDownload {
+		plan.downloadExactly(version)
+	}
+
+	return plan
+}
+
+// PlanInRange plans the usage of the most recent kubectl binary inside of
+// the given range. When none is available, the most recent stable release
+// inside of the range is downloaded.
+func (v *Versioner) PlanInRange(versionRange *common.VersionRange, allowDownload bool, useLatestIfNoCompatible bool) Plan {
+	bins := v.kFinder.AllKubectlBinaries(true)
+
+	plan := Plan{}
+	var selected *KubectlBinary
+	for _, b := range bins {
+		decision := CandidateDecision{KubectlBinary: b, Reason: "outside of the requested range"}
+		if versionRange.Contains(b.Version) {
+			decision.Accepted = true
+			decision.Reason = "inside of the requested range"
+			if selected == nil {
+				// newest-first, the most recent binary inside of the range wins
+				selected = &b
+			}
+		}
+		plan.Candidates = append(plan.Candidates, decision)
+	}
+
+	if selected != nil {
+		plan.use(*selected, "most recent binary inside of the range")
+		return plan
+	}
+
+	plan.download(bins, allowDownload, useLatestIfNoCompatible, "no binary inside of the range found",
+		errors.New("no kubectl inside of the requested range is available"))
+	if plan.Action == ActionDownload {
+		plan.downloadRange = versionRange
+		plan.Reason = "no binary inside of the range found, the most recent stable release inside of it will be downloaded from the upstream mirror"
+	}
+
+	return plan
+}
+
+// use makes the plan use the given binary.
+func (p *Plan) use(b KubectlBinary, reason string) {
+	p.Action = ActionUse
+	p.Binary = b
+	p.Reason = reason
+	p.markSelected(b.Path)
+}
+
+// download makes the plan download the missing binary when allowed,
+// otherwise it falls back to the newest binary when useLatestIfNoCompatible
+// is set. The binaries must be sorted newest-first.
+func (p *Plan) download(bins KubectlBinaries, allowDownload bool, useLatestIfNoCompatible bool, missing string, err error) {
+	switch {
+	case allowDownload:
+		p.Action = ActionDownload
+		p.Reason = missing + ", it will be downloaded from the upstream mirror"
+		if useLatestIfNoCompatible && len(bins) > 0 {
+			p.Fallback = &bins[0]
+		}
+	case useLatestIfNoCompatible && len(bins) > 0:
+		p.Action = ActionUseLatest
+		p.Binary = bins[0]
+		p.Reason = missing + " and downloads are disabled, UseLatestIfNoCompatible picks the newest binary"
+		p.markSelected(bins[0].Path)
+	default:
+		p.Action = ActionFail
+		p.Reason = missing + " and downloads are disabled"
+		p.err = fmt.Errorf("%s, binary downloads from kubernetes' upstream mirror are disabled: %w", missing, err)
+	}
+}
+
+// downloadExactly makes ActionDownload download the given version.
+func (p *Plan) downloadExactly(version semver.Version) {
+	p.downloadVersion = version
+	p.Binary = KubectlBinary{
+		Path:    filepath.Join(common.LocalDownloadDir(), common.BuildKubectlNameForLocalBin(version)),
+		Version: common.ReleaseVersion(version),
+		Origin:  OriginLocal,
+	}
+}
+
+func (p *Plan) markSelected(path string) {
+	for i := range p.Candidates {
+		p.Candidates[i].Selected = p.Candidates[i].Path == path
+	}
+}
+
+// Execute carries out the given plan, downloading the missing kubectl
+// binary when needed. It returns the full path to the binary to use.
+func (v *Versioner) Execute(plan Plan) (string, error) {
+	switch plan.Action {
+	case ActionUse, ActionUseLatest:
+		return plan.Binary.Path, nil
+	case ActionFail:
+		return "", plan.err
+	}
+
+	filename, err := v.executeDownload(plan)
+	if err != nil {
+		if plan.Fallback != nil {
+			klog.Infof("download failed (%v); falling back to newest local kubectl %s at %s",
+				err, plan.Fallback.Version, plan.Fallback.Path)
+			return plan.Fallback.Path, nil
+		}
+		return "", err
+	}
+
+	return filename, nil
+}
+
+func (v *Versioner) executeDownload(plan Plan) (string, error) {
+	version := plan.downloadVersion
+	if plan.downloadRange != nil {
+		var err error
+		version, err = v.downloader.LatestVersionInRange(plan.downloadRange)
+		if err != nil {
+			return "", fmt.Errorf("failed to find a kubectl release inside of the requested range: %w", err)
+		}
+	}
+
+	klog.Infof("Right kubectl missing, downloading version %s", version.String())
+
+	filename, err := v.downloadKubectl(version)
+	if err != nil {
+		return "", fmt.Errorf("failed to download kubectl %s: %w", version, err)
+	}
+
+	return filename, nil
+}
@@ -0,0 +1,167 @@
+package finder
+
+import (
+	"testing"
+
+	"github.com/blang/semver/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/flavio/kuberlr/internal/common"
+)
+
+func planTestBinaries() KubectlBinaries {
+	return KubectlBinaries{
+		{Version: semver.MustParse("1.30.1"), Path: "/bin/kubectl-1.30.1", Origin: OriginLocal},
+		{Version: semver.MustParse("1.28.7"), Path: "/bin/kubectl-1.28.7", Origin: OriginLocal},
+		{Version: semver.MustParse("1.28.2"), Path: "/bin/kubectl-1.28.2", Origin: OriginSystem},
+	}
+}
+
+func TestPlanCompatible(t *testing.T) {
+	tests := []struct {
+		name                    string
+		version                 string
+		allowDownload           bool
+		useLatestIfNoCompatible bool
+		expectedAction          string
+		expectedPath            string
+		expectedAccepted        []bool
+		expectsFallback         bool
+	}{
+		{
+			name:             "best compatible binary",
+			version:          "1.28.3",
+			expectedAction:   ActionUse,
+			expectedPath:     "/bin/kubectl-1.28.2",
+			expectedAccepted: []bool{false, true, true},
+		},
+		{
+			name:             "download",
+			version:          "1.33.0",
+			allowDownload:    true,
+			expectedAction:   ActionDownload,
+			expectedPath:     "kubectl1.33.0",
+			expectedAccepted: []bool{false, false, false},
+		},
+		{
+			name:                    "download with fallback",
+			version:                 "1.33.0",
+			allowDownload:           true,
+			useLatestIfNoCompatible: true,
+			expectedAction:          ActionDownload,
+			expectedPath:            "kubectl1.33.0",
+			expectedAccepted:        []bool{false, false, false},
+			expectsFallback:         true,
+		},
+		{
+			name:                    "newest binary",
+			version:                 "1.33.0",
+			useLatestIfNoCompatible: true,
+			expectedAction:          ActionUseLatest,
+			expectedPath:            "/bin/kubectl-1.30.1",
+			expectedAccepted:        []bool{false, false, false},
+		},
+		{
+			name:             "fail",
+			version:          "1.33.0",
+			expectedAction:   ActionFail,
+			expectedAccepted: []bool{false, false, false},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			finderMock := NewMockiFinder(t)
+			finderMock.EXPECT().AllKubectlBinaries(true).Return(planTestBinaries())
+			v := &Versioner{kFinder: finderMock, downloader: NewMockdownloadHelper(t)}
+
+			plan := v.PlanCompatible(semver.MustParse(tt.version), tt.allowDownload, tt.useLatestIfNoCompatible)
+
+			assert.Equal(t, tt.expectedAction, plan.Action)
+			assert.Contains(t, plan.Binary.Path, tt.expectedPath)
+			assert.Equal(t, tt.expectsFallback, plan.Fallback != nil)
+			require.Len(t, plan.Candidates, len(tt.expectedAccepted))
+			for i, c := range plan.Candidates {
+				assert.Equal(t, tt.expectedAccepted[i], c.Accepted, c.Path)
+				assert.Equal(t, tt.expectedAccepted[i], c.Score != nil, c.Path)
+				assert.Equal(t, c.Path == plan.Binary.Path, c.Selected, c.Path)
+				assert.NotEmpty(t, c.Reason)
+			}
+
+			if tt.expectedAction == ActionDownload {
+				// covered by the tests of EnsureCompatibleKubectlAvailable
+				return
+			}
+			path, err := v.Execute(plan)
+			if tt.expectedAction == ActionFail {
+				require.Error(t, err)
+				assert.True(t, common.IsNoVersionFound(err))
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedPath, path)
+		})
+	}
+}
+
+func TestPlanInRange(t *testing.T) {
+	versionRange, err := common.ParseVersionRange("1.28")
+	require.NoError(t, err)
+
+	finderMock := NewMockiFinder(t)
+	finderMock.EXPECT().AllKubectlBinaries(true).Return(planTestBinaries())
+	v := &Versioner{kFinder: finderMock, downloader: NewMockdownloadHelper(t)}
+
+	plan := v.PlanInRange(versionRange, true, false)
+
+	assert.Equal(t, ActionUse, plan.Action)
+	assert.Equal(t, "/bin/kubectl-1.28.7", plan.Binary.Path)
+	require.Len(t, plan.Candidates, 3)
+	assert.False(t, plan.Candidates[0].Accepted)
+	assert.True(t, plan.Candidates[1].Accepted)
+	assert.True(t, plan.Candidates[1].Selected)
+	assert.True(t, plan.Candidates[2].Accepted)
+	assert.False(t, plan.Candidates[2].Selected)
+}
+
+func TestPlanExact(t *testing.T) {
+	tests := []struct {
+		name           string
+		version        string
+		expectedAction string
+		expectedPath   string
+	}{
+		{
+			name:           "binary with the version",
+			version:        "1.28.2",
+			expectedAction: ActionUse,
+			expectedPath:   "/bin/kubectl-1.28.2",
+		},
+		{
+			// 1.28.2 and 1.28.7 are compatible, but not the requested version
+			name:           "download",
+			version:        "1.28.3",
+			expectedAction: ActionDownload,
+			expectedPath:   "kubectl1.28.3",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			finderMock := NewMockiFinder(t)
+			finderMock.EXPECT().AllKubectlBinaries(true).Return(planTestBinaries())
+			v := &Versioner{kFinder: finderMock, downloader: NewMockdownloadHelper(t)}
+
+			plan := v.PlanExact(semver.MustParse(tt.version), true, false)
+
+			assert.Equal(t, tt.expectedAction, plan.Action)
+			assert.Contains(t, plan.Binary.Path, tt.expectedPath)
+			require.Len(t, plan.Candidates, 3)
+			for _, c := range plan.Candidates {
+				assert.Equal(t, c.Version.String() == tt.version, c.Accepted, c.Path)
+				assert.Nil(t, c.Score, c.Path)
+			}
+		})
+	}
+}
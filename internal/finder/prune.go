@@ -0,0 +1,170 @@
+package finder
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"k8s.io/klog"
+
+	"github.com/flavio/kuberlr/internal/common"
+)
+
+// PruneOptions tunes which kubectl binaries downloaded by kuberlr are
+// removed by PlanPrune.
+type PruneOptions struct {
+	// OlderThan, when not zero, restricts the removal to the binaries that
+	// have not been used for longer than it
+	OlderThan time.Duration
+	// KeepLatestPerMinor keeps the most recent patch release of each
+	// minor version
+	KeepLatestPerMinor bool
+	// Keep holds the paths of the binaries that must never be removed
+	Keep map[string]bool
+}
+
+// LastActivity returns when kuberlr used the binary for the last time. The
+// modification time of the file, which is when the binary has been
+// downloaded, is returned when kuberlr never used it.
+func LastActivity(b KubectlBinary) time.Time {
+	if !b.LastUsed.IsZero() {
+		return b.LastUsed
+	}
+
+	info, err := os.Stat(b.Path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
+
+// PlanPrune returns the binaries downloaded by kuberlr that have to be
+// removed according to the given options. System-wide binaries are never
+// part of the plan.
+func PlanPrune(bins KubectlBinaries, opts PruneOptions, now time.Time) KubectlBinaries {
+	latestPerMinor := map[string]KubectlBinary{}
+	if opts.KeepLatestPerMinor {
+		for _, b := range bins {
+			if b.Origin != OriginLocal {
+				continue
+			}
+			minor := fmt.Sprintf("%d.%d", b.Version.Major, b.Version.Minor)
+			if latest, found := latestPerMinor[minor]; !found || b.Version.GT(latest.Version) {
+				latestPerMinor[minor] = b
+			}
+		}
+	}
+
+	kept := map[string]bool{}
+	for _, b := range latestPerMinor {
+		kept[b.Path] = true
+	}
+
+	plan := KubectlBinaries{}
+	for _, b := range bins {
+		if b.Origin != OriginLocal || kept[b.Path] || opts.Keep[b.Path] {
+			continue
+		}
+		if opts.OlderThan > 0 && now.Sub(LastActivity(b)) < opts.OlderThan {
+			continue
+		}
+		plan = append(plan, b)
+	}
+
+	return plan
+}
+
+// PlanEviction returns the binaries downloaded by kuberlr that have to be
+// removed to bring their total size under maxSize bytes. The least recently
+// used binaries are evicted first, the ones listed by keep are never evicted.
+func PlanEviction(bins KubectlBinaries, maxSize int64, keep map[string]bool) (KubectlBinaries, error) {
+	type sizedBinary struct {
+		KubectlBinary
+		size         int64
+		lastActivity time.Time
+	}
+
+	var total int64
+	candidates := []sizedBinary{}
+	for _, b := range bins {
+		if b.Origin != OriginLocal {
+			continue
+		}
+		info, err := os.Stat(b.Path)
+		if err != nil {
+			return nil, err
+		}
+		total += info.Size()
+		if !keep[b.Path] {
+			candidates = append(candidates, sizedBinary{b, info.Size(), LastActivity(b)})
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].lastActivity.Before(candidates[j].lastActivity)
+	})
+
+	plan := KubectlBinaries{}
+	for _, c := range candidates {
+		if total <= maxSize {
+			break
+		}
+		plan = append(plan, c.KubectlBinary)
+		total -= c.size
+	}
+
+	return plan, nil
+}
+
+// RemoveKubectlBinaries deletes the given binaries and drops their usage
+// information from the file stored at usagePath. All the binaries are
+// processed even when some of them cannot be removed.
+func RemoveKubectlBinaries(bins KubectlBinaries, usagePath string) error {
+	var errs []error
+	removed := []string{}
+	for _, b := range bins {
+		if err := os.Remove(b.Path); err != nil && !os.IsNotExist(err) {
+			errs = append(errs, err)
+			continue
+		}
+		removed = append(removed, b.Path)
+	}
+
+	if usagePath != "" && len(removed) > 0 {
+		err := UpdateUsage(usagePath, func(usage *Usage) bool {
+			for _, path := range removed {
+				usage.Forget(path)
+			}
+			return true
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("cannot update kubectl usage: %w", err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// EnforceMaxCacheSize removes the least recently used binaries downloaded
+// by kuberlr until their total size is under maxSize bytes. The binary
+// stored at keepPath, usually the one just downloaded, is never removed.
+// The removed binaries are returned.
+func (f *KubectlFinder) EnforceMaxCacheSize(maxSize int64, keepPath string) (KubectlBinaries, error) {
+	bins, err := f.LocalKubectlBinaries()
+	if err != nil {
+		return nil, err
+	}
+
+	plan, err := PlanEviction(bins, maxSize, map[string]bool{keepPath: true})
+	if err != nil {
+		return nil, fmt.Errorf("cannot compute the size of the kubectl binaries: %w", err)
+	}
+
+	for _, b := range plan {
+		klog.V(common.VerbosityOne).Infof("evicting kubectl %s (%s) to honor MaxCacheSize", b.Version, b.Path)
+	}
+
+	return plan, f.RemoveKubectlBinaries(plan)
+}
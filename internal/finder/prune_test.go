@@ -0,0 +1,146 @@
+package finder
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func binaryPaths(bins KubectlBinaries) []string {
+	paths := []string{}
+	for _, b := range bins {
+		paths = append(paths, b.Path)
+	}
+	return paths
+}
+
+func TestPlanPrune(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+
+	bins := fakeKubectlBinaries(dir, []string{"1.27.2", "1.28.1", "1.28.4", "1.29.0"}, &localKubectlNamer{})
+	require.NoError(t, createFakeKubectlBinaries(bins))
+	bins[0].LastUsed = now.Add(-60 * 24 * time.Hour)
+	bins[1].LastUsed = now.Add(-40 * 24 * time.Hour)
+	bins[2].LastUsed = now.Add(-time.Hour)
+	bins[3].LastUsed = now.Add(-50 * 24 * time.Hour)
+	bins = append(bins, fakeKubectlBinaries("/usr/bin", []string{"1.20.0"}, &systemKubectlNamer{})...)
+
+	plan := PlanPrune(bins, PruneOptions{}, now)
+	assert.Equal(t, binaryPaths(bins[:4]), binaryPaths(plan), "system-wide binaries must never be pruned")
+
+	plan = PlanPrune(bins, PruneOptions{OlderThan: 30 * 24 * time.Hour}, now)
+	assert.Equal(t, binaryPaths(KubectlBinaries{bins[0], bins[1], bins[3]}), binaryPaths(plan))
+
+	plan = PlanPrune(bins, PruneOptions{KeepLatestPerMinor: true}, now)
+	assert.Equal(t, binaryPaths(KubectlBinaries{bins[1]}), binaryPaths(plan))
+
+	plan = PlanPrune(bins, PruneOptions{
+		OlderThan: 30 * 24 * time.Hour,
+		Keep:      map[string]bool{bins[0].Path: true},
+	}, now)
+	assert.Equal(t, binaryPaths(KubectlBinaries{bins[1], bins[3]}), binaryPaths(plan))
+}
+
+func TestPlanEviction(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+
+	bins := fakeKubectlBinaries(dir, []string{"1.27.2", "1.28.4", "1.29.0"}, &localKubectlNamer{})
+	for i := range bins {
+		require.NoError(t, os.WriteFile(bins[i].Path, make([]byte, 100), 0o600))
+	}
+	bins[0].LastUsed = now.Add(-time.Hour)
+	bins[1].LastUsed = now.Add(-2 * time.Hour)
+	bins[2].LastUsed = now.Add(-3 * time.Hour)
+
+	plan, err := PlanEviction(bins, 300, nil)
+	require.NoError(t, err)
+	assert.Empty(t, plan)
+
+	// least recently used first, the kept binary is never evicted
+	plan, err = PlanEviction(bins, 150, map[string]bool{bins[2].Path: true})
+	require.NoError(t, err)
+	assert.Equal(t, binaryPaths(KubectlBinaries{bins[1], bins[0]}), binaryPaths(plan))
+}
+
+func TestRemoveKubectlBinaries(t *testing.T) {
+	dir := t.TempDir()
+	usagePath := filepath.Join(dir, UsageFileName)
+
+	bins := fakeKubectlBinaries(dir, []string{"1.28.4", "1.29.0"}, &localKubectlNamer{})
+	require.NoError(t, createFakeKubectlBinaries(bins))
+	for _, b := range bins {
+		require.NoError(t, RecordUsage(usagePath, filepath.Dir(b.Path), b.Path))
+	}
+
+	require.NoError(t, RemoveKubectlBinaries(bins[:1], usagePath))
+
+	_, err := os.Stat(bins[0].Path)
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(bins[1].Path)
+	require.NoError(t, err)
+
+	usage, err := LoadUsage(usagePath)
+	require.NoError(t, err)
+	assert.NotContains(t, usage.LastUsed, bins[0].Path)
+	assert.Contains(t, usage.LastUsed, bins[1].Path)
+}
+
+func TestFinderRemoveKubectlBinaries(t *testing.T) {
+	td, err := setupFilesystemTest()
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, teardownFilesystemTest(td))
+	}()
+	td.Finder.usagePath = filepath.Join(td.FakeHome, UsageFileName)
+	td.Finder.probe = newVersionProbe(filepath.Join(td.FakeHome, VersionCacheFileName))
+
+	writeFakeKubectlScript(t, filepath.Join(td.FakeSysBinPath, "kubectl"), "v1.30.2")
+	bins, err := td.Finder.SystemKubectlBinaries()
+	require.NoError(t, err)
+	require.Len(t, bins, 1)
+	require.NoError(t, UpdateUsage(td.Finder.usagePath, func(usage *Usage) bool {
+		usage.Touch(bins[0].Path)
+		return true
+	}))
+	require.Contains(t, td.Finder.probe.cache, bins[0].Path)
+
+	require.NoError(t, td.Finder.RemoveKubectlBinaries(bins))
+
+	_, err = os.Stat(bins[0].Path)
+	assert.True(t, os.IsNotExist(err))
+	assert.NotContains(t, newVersionProbe(td.Finder.probe.cachePath).cache, bins[0].Path)
+	usage, err := LoadUsage(td.Finder.usagePath)
+	require.NoError(t, err)
+	assert.Empty(t, usage.LastUsed)
+}
+
+func TestFinderEnforceMaxCacheSize(t *testing.T) {
+	td, err := setupFilesystemTest()
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, teardownFilesystemTest(td))
+	}()
+	td.Finder.usagePath = filepath.Join(td.FakeHome, UsageFileName)
+	td.Finder.probe = newVersionProbe(filepath.Join(td.FakeHome, VersionCacheFileName))
+
+	bins := fakeKubectlBinaries(td.FakeHome, []string{"1.27.2", "1.28.4", "1.29.0"}, &localKubectlNamer{})
+	for _, b := range bins {
+		require.NoError(t, os.WriteFile(b.Path, make([]byte, 100), 0o600))
+	}
+	keepPath := bins[0].Path
+
+	evicted, err := td.Finder.EnforceMaxCacheSize(150, keepPath)
+	require.NoError(t, err)
+	assert.Len(t, evicted, 2)
+	assert.NotContains(t, binaryPaths(evicted), keepPath)
+
+	left, err := td.Finder.LocalKubectlBinaries()
+	require.NoError(t, err)
+	assert.Equal(t, []string{keepPath}, binaryPaths(left))
+}
@@ -0,0 +1,139 @@
+package finder
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/blang/semver/v4"
+)
+
+const (
+	// RankExactMinor prefers the binaries with the same minor version of
+	// the API server.
+	RankExactMinor = "exact-minor"
+	// RankClosestPatch prefers the binaries whose patch level is the
+	// closest to the one of the API server. Only binaries with the same
+	// minor version of the API server are taken into account. A binary
+	// with exactly the version of the API server, pre-release identifiers
+	// included, is preferred over the other ones with the same patch level.
+	RankClosestPatch = "closest-patch"
+	// RankLocal prefers the binaries downloaded by kuberlr over the
+	// system-wide ones.
+	RankLocal = "local"
+	// RankRecentlyUsed prefers the binaries that have been used recently.
+	RankRecentlyUsed = "recently-used"
+)
+
+// maxCriterionScore is the highest score a binary can get from a single
+// ranking criterion.
+const maxCriterionScore = 99
+
+// RankingPreferences is the ordered list of criteria used to rank the
+// compatible kubectl binaries. The first criterion is the most important
+// one, the following ones are used to break ties. When all the criteria
+// are even, the most recent version wins.
+type RankingPreferences []string
+
+// DefaultRankingPreferences returns the criteria used by default to rank
+// the compatible kubectl binaries.
+func DefaultRankingPreferences() RankingPreferences {
+	return RankingPreferences{RankExactMinor, RankClosestPatch, RankLocal, RankRecentlyUsed}
+}
+
+// Validate returns an error when the preferences contain an unknown or a
+// duplicated criterion.
+func (p RankingPreferences) Validate() error {
+	seen := map[string]bool{}
+	for _, criterion := range p {
+		switch criterion {
+		case RankExactMinor, RankClosestPatch, RankLocal, RankRecentlyUsed:
+		default:
+			return fmt.Errorf(
+				"unknown ranking criterion %q, valid values are: %s, %s, %s, %s",
+				criterion, RankExactMinor, RankClosestPatch, RankLocal, RankRecentlyUsed)
+		}
+		if seen[criterion] {
+			return fmt.Errorf("ranking criterion %q specified more than once", criterion)
+		}
+		seen[criterion] = true
+	}
+
+	return nil
+}
+
+// RankedKubectlBinary is a kubectl binary compatible with the API server,
+// together with its ranking score. The higher the score, the better.
+type RankedKubectlBinary struct {
+	KubectlBinary
+	Score uint64
+}
+
+// RankedKubectlBinaries is a list of RankedKubectlBinary objects.
+type RankedKubectlBinaries []RankedKubectlBinary
+
+// RankKubectlBinaries returns the binaries that are compatible with the given
+// API server version according to the skew policy, best candidate first.
+// Important: the `bins` parameter must be sorted in descending order.
+func RankKubectlBinaries(
+	requestedVersion semver.Version,
+	bins KubectlBinaries,
+	policy SkewPolicy,
+	preferences RankingPreferences,
+) RankedKubectlBinaries {
+	now := time.Now()
+	ranked := RankedKubectlBinaries{}
+	for _, b := range bins {
+		if !policy.Allows(requestedVersion, b.Version) {
+			continue
+		}
+
+		var score uint64
+		for _, criterion := range preferences {
+			score = score*(maxCriterionScore+1) + criterionScore(criterion, requestedVersion, b, now)
+		}
+		ranked = append(ranked, RankedKubectlBinary{KubectlBinary: b, Score: score})
+	}
+
+	// the sort is stable: on ties, the most recent version wins
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return ranked[i].Score > ranked[j].Score
+	})
+
+	return ranked
+}
+
+func criterionScore(criterion string, requestedVersion semver.Version, b KubectlBinary, now time.Time) uint64 {
+	sameMinor := b.Version.Major == requestedVersion.Major && b.Version.Minor == requestedVersion.Minor
+
+	switch criterion {
+	case RankExactMinor:
+		if sameMinor {
+			return maxCriterionScore
+		}
+	case RankClosestPatch:
+		if b.Version.EQ(requestedVersion) {
+			// the same patch level and the same pre-release identifiers
+			return maxCriterionScore
+		}
+		if sameMinor {
+			distance := b.Version.Patch - requestedVersion.Patch
+			if requestedVersion.Patch > b.Version.Patch {
+				distance = requestedVersion.Patch - b.Version.Patch
+			}
+			return maxCriterionScore - 1 - min(distance, maxCriterionScore-1)
+		}
+	case RankLocal:
+		if b.Origin == OriginLocal {
+			return maxCriterionScore
+		}
+	case RankRecentlyUsed:
+		if !b.LastUsed.IsZero() {
+			days := uint64(max(now.Sub(b.LastUsed), 0) / (24 * time.Hour)) //nolint: mnd // hours in a day
+			// used today is better than never used
+			return maxCriterionScore - min(days, maxCriterionScore-1)
+		}
+	}
+
+	return 0
+}
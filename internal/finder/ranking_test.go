@@ -0,0 +1,117 @@
+package finder
+
+import (
+	"testing"
+	"time"
+
+	"github.com/blang/semver/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRankKubectlBinaries(t *testing.T) {
+	now := time.Now()
+
+	// newest-first
+	kubectlBins := KubectlBinaries{
+		{Version: semver.MustParse("1.29.2"), Path: "local/kubectl1.29.2", Origin: OriginLocal},
+		{Version: semver.MustParse("1.28.9"), Path: "local/kubectl1.28.9", Origin: OriginLocal},
+		{Version: semver.MustParse("1.28.3"), Path: "system/kubectl1.28.3", Origin: OriginSystem},
+		{Version: semver.MustParse("1.28.3"), Path: "local/kubectl1.28.3", Origin: OriginLocal},
+		{Version: semver.MustParse("1.28.0"), Path: "system/kubectl1.28", Origin: OriginSystem, LastUsed: now},
+		{Version: semver.MustParse("1.25.0"), Path: "system/kubectl1.25", Origin: OriginSystem},
+	}
+
+	tests := []struct {
+		name          string
+		preferences   RankingPreferences
+		expectedPaths []string
+	}{
+		{
+			name:        "default preferences",
+			preferences: DefaultRankingPreferences(),
+			expectedPaths: []string{
+				"local/kubectl1.28.3",
+				"system/kubectl1.28.3",
+				"system/kubectl1.28",
+				"local/kubectl1.28.9",
+				"local/kubectl1.29.2",
+			},
+		},
+		{
+			name:        "no preferences, most recent version wins",
+			preferences: RankingPreferences{},
+			expectedPaths: []string{
+				"local/kubectl1.29.2",
+				"local/kubectl1.28.9",
+				"system/kubectl1.28.3",
+				"local/kubectl1.28.3",
+				"system/kubectl1.28",
+			},
+		},
+		{
+			name:        "recently used first",
+			preferences: RankingPreferences{RankRecentlyUsed, RankExactMinor},
+			expectedPaths: []string{
+				"system/kubectl1.28",
+				"local/kubectl1.28.9",
+				"system/kubectl1.28.3",
+				"local/kubectl1.28.3",
+				"local/kubectl1.29.2",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ranked := RankKubectlBinaries(
+				semver.MustParse("1.28.4"),
+				kubectlBins,
+				UpstreamSkewPolicy(),
+				tt.preferences)
+
+			actualPaths := []string{}
+			for _, r := range ranked {
+				actualPaths = append(actualPaths, r.Path)
+			}
+			assert.Equal(t, tt.expectedPaths, actualPaths)
+
+			for i := 1; i < len(ranked); i++ {
+				assert.GreaterOrEqual(t, ranked[i-1].Score, ranked[i].Score)
+			}
+		})
+	}
+}
+
+func TestRankingPreferencesValidate(t *testing.T) {
+	require.NoError(t, DefaultRankingPreferences().Validate())
+	require.NoError(t, RankingPreferences{}.Validate())
+	require.Error(t, RankingPreferences{"newest"}.Validate())
+	require.Error(t, RankingPreferences{RankLocal, RankLocal}.Validate())
+}
+
+func TestRankKubectlBinariesPreRelease(t *testing.T) {
+	// newest-first
+	kubectlBins := KubectlBinaries{
+		{Version: semver.MustParse("1.31.0"), Path: "local/kubectl1.31.0", Origin: OriginLocal},
+		{Version: semver.MustParse("1.31.0-rc.1"), Path: "local/kubectl1.31.0-rc.1", Origin: OriginLocal},
+		{Version: semver.MustParse("1.31.0-rc.0"), Path: "local/kubectl1.31.0-rc.0", Origin: OriginLocal},
+		{Version: semver.MustParse("1.30.2"), Path: "local/kubectl1.30.2", Origin: OriginLocal},
+	}
+
+	ranked := RankKubectlBinaries(
+		semver.MustParse("1.31.0-rc.1"),
+		kubectlBins,
+		UpstreamSkewPolicy(),
+		DefaultRankingPreferences())
+	require.Len(t, ranked, 4)
+	assert.Equal(t, "local/kubectl1.31.0-rc.1", ranked[0].Path)
+
+	ranked = RankKubectlBinaries(
+		semver.MustParse("1.31.0"),
+		kubectlBins,
+		UpstreamSkewPolicy(),
+		DefaultRankingPreferences())
+	require.Len(t, ranked, 4)
+	assert.Equal(t, "local/kubectl1.31.0", ranked[0].Path)
+}
@@ -0,0 +1,106 @@
+package finder
+
+import (
+	"fmt"
+
+	"github.com/blang/semver/v4"
+)
+
+const (
+	// SkewPolicyStrict accepts only kubectl binaries with the same minor
+	// version of the API server.
+	SkewPolicyStrict = "strict"
+	// SkewPolicyUpstream accepts kubectl binaries within one minor version
+	// (older or newer) of the API server, like the kubernetes version skew
+	// policy does.
+	SkewPolicyUpstream = "upstream"
+	// SkewPolicyOlderOnly accepts kubectl binaries with the same minor
+	// version of the API server or one minor version older.
+	SkewPolicyOlderOnly = "older-only"
+	// SkewPolicyNewerOnly accepts kubectl binaries with the same minor
+	// version of the API server or one minor version newer.
+	SkewPolicyNewerOnly = "newer-only"
+	// SkewPolicyCustom accepts kubectl binaries within a user defined
+	// number of minor versions of the API server.
+	SkewPolicyCustom = "custom"
+)
+
+// SkewPolicy defines which kubectl versions are compatible with a given
+// version of the API server.
+type SkewPolicy struct {
+	Name string
+	// MinorsBelow is how many minor versions kubectl can be older than the API server
+	MinorsBelow uint64
+	// MinorsAbove is how many minor versions kubectl can be newer than the API server
+	MinorsAbove uint64
+}
+
+// NewSkewPolicy returns the skew policy with the given name. The number of
+// minor versions are taken into account only by the `custom` policy.
+func NewSkewPolicy(name string, minorsBelow, minorsAbove uint64) (SkewPolicy, error) {
+	switch name {
+	case SkewPolicyStrict:
+		return SkewPolicy{Name: name}, nil
+	case "", SkewPolicyUpstream:
+		return UpstreamSkewPolicy(), nil
+	case SkewPolicyOlderOnly:
+		return SkewPolicy{Name: name, MinorsBelow: 1}, nil
+	case SkewPolicyNewerOnly:
+		return SkewPolicy{Name: name, MinorsAbove: 1}, nil
+	case SkewPolicyCustom:
+		return SkewPolicy{Name: name, MinorsBelow: minorsBelow, MinorsAbove: minorsAbove}, nil
+	default:
+		return SkewPolicy{}, fmt.Errorf(
+			"unknown skew policy %q, valid values are: %s, %s, %s, %s, %s",
+			name,
+			SkewPolicyStrict, SkewPolicyUpstream, SkewPolicyOlderOnly, SkewPolicyNewerOnly, SkewPolicyCustom)
+	}
+}
+
+// UpstreamSkewPolicy returns the skew policy defined by kubernetes: kubectl
+// is supported within one minor version (older or newer) of the API server.
+func UpstreamSkewPolicy() SkewPolicy {
+	return SkewPolicy{Name: SkewPolicyUpstream, MinorsBelow: 1, MinorsAbove: 1}
+}
+
+// Bounds returns the lowest kubectl version compatible with the given API
+// server version and the first version that is no longer compatible.
+func (p SkewPolicy) Bounds(v semver.Version) (semver.Version, semver.Version) {
+	lower := semver.Version{Major: v.Major}
+	if v.Minor > p.MinorsBelow {
+		lower.Minor = v.Minor - p.MinorsBelow
+	}
+
+	upper := semver.Version{
+		Major: v.Major,
+		Minor: v.Minor + p.MinorsAbove + 1,
+	}
+
+	return lower, upper
+}
+
+// RangeRule returns the semver range of the kubectl versions compatible
+// with the given API server version.
+func (p SkewPolicy) RangeRule(v semver.Version) string {
+	lower, upper := p.Bounds(v)
+	return fmt.Sprintf(">=%s <%s", lower.String(), upper.String())
+}
+
+// Allows returns true when the given kubectl version is compatible with the
+// API server version. Pre-releases are compatible when their final release
+// is: kubectl 1.29.0-rc.1 is handled like 1.29.0.
+func (p SkewPolicy) Allows(serverVersion, kubectlVersion semver.Version) bool {
+	lower, upper := p.Bounds(serverVersion)
+	release := semver.Version{
+		Major: kubectlVersion.Major,
+		Minor: kubectlVersion.Minor,
+		Patch: kubectlVersion.Patch,
+	}
+
+	return release.GTE(lower) && release.LT(upper)
+}
+
+// String returns a human description of the policy.
+func (p SkewPolicy) String() string {
+	return fmt.Sprintf("%s (-%d/+%d minor versions)", p.Name, p.MinorsBelow, p.MinorsAbove)
+}
@@ -0,0 +1,92 @@
+package finder
+
+import (
+	"testing"
+
+	"github.com/blang/semver/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/flavio/kuberlr/internal/common"
+)
+
+func TestSkewPolicyRangeRule(t *testing.T) {
+	serverVersion := semver.MustParse("1.28.4")
+
+	tests := []struct {
+		policy      string
+		minorsBelow uint64
+		minorsAbove uint64
+		expected    string
+	}{
+		{policy: SkewPolicyStrict, expected: ">=1.28.0 <1.29.0"},
+		{policy: SkewPolicyUpstream, expected: ">=1.27.0 <1.30.0"},
+		{policy: "", expected: ">=1.27.0 <1.30.0"},
+		{policy: SkewPolicyOlderOnly, expected: ">=1.27.0 <1.29.0"},
+		{policy: SkewPolicyNewerOnly, expected: ">=1.28.0 <1.30.0"},
+		{policy: SkewPolicyCustom, minorsBelow: 3, minorsAbove: 0, expected: ">=1.25.0 <1.29.0"},
+		{policy: SkewPolicyCustom, minorsBelow: 30, minorsAbove: 2, expected: ">=1.0.0 <1.31.0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			policy, err := NewSkewPolicy(tt.policy, tt.minorsBelow, tt.minorsAbove)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, policy.RangeRule(serverVersion))
+		})
+	}
+}
+
+func TestUnknownSkewPolicy(t *testing.T) {
+	_, err := NewSkewPolicy("loose", 0, 0)
+	assert.Error(t, err)
+}
+
+func TestFindCompatibleKubectlWithSkewPolicy(t *testing.T) {
+	kubectlBins := KubectlBinaries{
+		{Version: semver.MustParse("1.29.1"), Path: "path/to/kubectl-1.29.1"},
+		{Version: semver.MustParse("1.27.6"), Path: "path/to/kubectl-1.27.6"},
+	}
+	serverVersion := semver.MustParse("1.28.4")
+
+	tests := []struct {
+		policy       string
+		expectedPath string
+	}{
+		{policy: SkewPolicyUpstream, expectedPath: "path/to/kubectl-1.29.1"},
+		{policy: SkewPolicyOlderOnly, expectedPath: "path/to/kubectl-1.27.6"},
+		{policy: SkewPolicyNewerOnly, expectedPath: "path/to/kubectl-1.29.1"},
+		{policy: SkewPolicyStrict, expectedPath: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			policy, err := NewSkewPolicy(tt.policy, 0, 0)
+			require.NoError(t, err)
+
+			actual, err := findCompatibleKubectl(serverVersion, kubectlBins, policy, DefaultRankingPreferences())
+			if tt.expectedPath != "" {
+				require.NoError(t, err)
+				assert.Equal(t, tt.expectedPath, actual.Path)
+				return
+			}
+
+			var noVersionFoundErr *common.NoVersionFoundError
+			require.ErrorAs(t, err, &noVersionFoundErr)
+			assert.Equal(t, ">=1.28.0 <1.29.0", noVersionFoundErr.Range)
+			assert.Contains(t, err.Error(), ">=1.28.0 <1.29.0")
+		})
+	}
+}
+
+func TestSkewPolicyAllowsPreReleases(t *testing.T) {
+	policy := UpstreamSkewPolicy()
+	serverVersion := semver.MustParse("1.30.0-rc.1")
+
+	for _, v := range []string{"1.29.0-rc.0", "1.29.8", "1.30.0-alpha.1", "1.30.0-rc.1", "1.31.0-beta.2"} {
+		assert.True(t, policy.Allows(serverVersion, semver.MustParse(v)), v)
+	}
+	for _, v := range []string{"1.28.9", "1.32.0-alpha.0", "1.32.0"} {
+		assert.False(t, policy.Allows(serverVersion, semver.MustParse(v)), v)
+	}
+}
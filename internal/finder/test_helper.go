@@ -37,6 +37,11 @@ func (n *systemKubectlNamer) Compute(v semver.Version) string {
 func fakeKubectlBinaries(path string, versions []string, nameBuilder kubectlNamer) KubectlBinaries {
 	bins := KubectlBinaries{}
 
+	realDir, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		realDir = path
+	}
+
 	for _, v := range versions {
 		version := semver.MustParse(v)
 
@@ -46,13 +51,16 @@ func fakeKubectlBinaries(path string, versions []string, nameBuilder kubectlName
 			version.Patch = 0
 		}
 
-		bin := filepath.Join(path, nameBuilder.Compute(version))
+		name := nameBuilder.Compute(version)
 
 		bins = append(
 			bins,
 			KubectlBinary{
-				Version: version,
-				Path:    bin,
+				Version:  version,
+				Path:     filepath.Join(path, name),
+				Origin:   nameBuilder.ID(),
+				Dir:      path,
+				RealPath: filepath.Join(realDir, name),
 			})
 	}
 
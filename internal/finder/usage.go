@@ -0,0 +1,179 @@
+package finder
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/flavio/kuberlr/internal/common"
+)
+
+const (
+	// UsageFileName is the name of the file where kuberlr records when the
+	// kubectl binaries have been used for the last time.
+	UsageFileName = "usage.json"
+
+	// UsageRecordInterval is how often the usage of a kubectl binary is
+	// recorded: using it again before the interval is over doesn't rewrite
+	// the usage file.
+	UsageRecordInterval = time.Hour
+
+	// usageLockTimeout is how long to wait for other kuberlr processes to
+	// release the lock of the usage file.
+	usageLockTimeout = time.Second
+
+	// usageStaleLockAge is the age after which the lock of the usage file
+	// is considered left behind by a process that died while holding it.
+	usageStaleLockAge = 10 * time.Second
+
+	// usageLockRetryDelay is the pause between two attempts to acquire the
+	// lock of the usage file.
+	usageLockRetryDelay = 10 * time.Millisecond
+)
+
+// errUsageLocked is returned when the lock of the usage file cannot be
+// acquired in time.
+var errUsageLocked = errors.New("the usage file is locked by another process")
+
+// Usage keeps track of when the kubectl binaries have been used for the
+// last time.
+type Usage struct {
+	path string
+	// LastUsed maps the path of a kubectl binary to its last usage
+	LastUsed map[string]time.Time `json:"lastUsed"`
+}
+
+// DefaultUsagePath returns the path to the file used to track the usage
+// of the kubectl binaries.
+func DefaultUsagePath() string {
+	return filepath.Join(common.KuberlrDir(), UsageFileName)
+}
+
+// LoadUsage reads the usage information stored at the given path. An
+// empty object is returned when the file doesn't exist.
+func LoadUsage(path string) (*Usage, error) {
+	usage := &Usage{
+		path:     path,
+		LastUsed: map[string]time.Time{},
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return usage, nil
+		}
+		return usage, err
+	}
+
+	if err = json.Unmarshal(data, usage); err != nil {
+		return usage, err
+	}
+	if usage.LastUsed == nil {
+		usage.LastUsed = map[string]time.Time{}
+	}
+
+	return usage, nil
+}
+
+// Touch records the given kubectl binary has just been used.
+func (u *Usage) Touch(binPath string) {
+	u.LastUsed[binPath] = time.Now().UTC()
+}
+
+// Forget drops the usage information of the given kubectl binary, it's
+// meant to be called once the binary has been removed.
+func (u *Usage) Forget(binPath string) {
+	delete(u.LastUsed, binPath)
+}
+
+// Save writes the usage information back to disk.
+func (u *Usage) Save() error {
+	data, err := json.Marshal(u)
+	if err != nil {
+		return err
+	}
+
+	return common.WriteFileAtomically(u.path, data)
+}
+
+// UpdateUsage changes the usage information stored at the given path.
+// The file is locked while being changed, hence concurrent kuberlr
+// processes do not overwrite each other changes. The file is written only
+// when update returns true.
+func UpdateUsage(usagePath string, update func(*Usage) bool) error {
+	unlock, err := lockUsage(usagePath)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	usage, err := LoadUsage(usagePath)
+	if err != nil {
+		return err
+	}
+	if !update(usage) {
+		return nil
+	}
+
+	return usage.Save()
+}
+
+// RecordUsage records the given kubectl binary has just been used. Only the
+// binaries downloaded by kuberlr inside of localDir are tracked, and their
+// usage is recorded at most once per UsageRecordInterval.
+func RecordUsage(usagePath, localDir, binPath string) error {
+	if filepath.Dir(binPath) != filepath.Clean(localDir) {
+		return nil
+	}
+
+	// the file is replaced atomically, reading it without holding the
+	// lock avoids contention when nothing has to be recorded
+	usage, err := LoadUsage(usagePath)
+	if err == nil && !usage.needsTouch(binPath) {
+		return nil
+	}
+
+	return UpdateUsage(usagePath, func(usage *Usage) bool {
+		if !usage.needsTouch(binPath) {
+			return false
+		}
+		usage.Touch(binPath)
+		return true
+	})
+}
+
+func (u *Usage) needsTouch(binPath string) bool {
+	return time.Since(u.LastUsed[binPath]) >= UsageRecordInterval
+}
+
+// lockUsage creates the lock file of the usage file stored at the given
+// path. The returned function releases the lock.
+func lockUsage(usagePath string) (func(), error) {
+	lockPath := usagePath + ".lock"
+	if err := os.MkdirAll(filepath.Dir(lockPath), 0o750); err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(usageLockTimeout)
+	for {
+		lock, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+		if err == nil {
+			lock.Close()
+			return func() { os.Remove(lockPath) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, err
+		}
+
+		if info, statErr := os.Stat(lockPath); statErr == nil && time.Since(info.ModTime()) > usageStaleLockAge {
+			os.Remove(lockPath)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, errUsageLocked
+		}
+		time.Sleep(usageLockRetryDelay)
+	}
+}
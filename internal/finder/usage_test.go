@@ -0,0 +1,107 @@
+package finder
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordUsage(t *testing.T) {
+	usagePath := filepath.Join(t.TempDir(), "kuberlr", UsageFileName)
+	localDir := t.TempDir()
+	localBin := filepath.Join(localDir, "kubectl1.28.4")
+
+	usage, err := LoadUsage(usagePath)
+	require.NoError(t, err)
+	assert.Empty(t, usage.LastUsed)
+
+	require.NoError(t, RecordUsage(usagePath, localDir, localBin))
+	// system-wide binaries are not tracked
+	require.NoError(t, RecordUsage(usagePath, localDir, "/usr/bin/kubectl1.28"))
+
+	usage, err = LoadUsage(usagePath)
+	require.NoError(t, err)
+	assert.Len(t, usage.LastUsed, 1)
+	lastUsed := usage.LastUsed[localBin]
+	assert.False(t, lastUsed.IsZero())
+
+	// used again before UsageRecordInterval: nothing is written
+	require.NoError(t, RecordUsage(usagePath, localDir, localBin))
+	usage, err = LoadUsage(usagePath)
+	require.NoError(t, err)
+	assert.Equal(t, lastUsed, usage.LastUsed[localBin])
+
+	// the usage is recorded again once the interval is over
+	usage.LastUsed[localBin] = lastUsed.Add(-UsageRecordInterval)
+	require.NoError(t, usage.Save())
+	require.NoError(t, RecordUsage(usagePath, localDir, localBin))
+	usage, err = LoadUsage(usagePath)
+	require.NoError(t, err)
+	assert.True(t, usage.LastUsed[localBin].After(lastUsed.Add(-UsageRecordInterval)))
+}
+
+func TestRecordUsageConcurrently(t *testing.T) {
+	usagePath := filepath.Join(t.TempDir(), UsageFileName)
+	localDir := t.TempDir()
+
+	const binaries = 20
+	var wg sync.WaitGroup
+	for i := 0; i < binaries; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			assert.NoError(t, RecordUsage(usagePath, localDir, filepath.Join(localDir, fmt.Sprintf("kubectl1.%d.0", i))))
+		}(i)
+	}
+	wg.Wait()
+
+	usage, err := LoadUsage(usagePath)
+	require.NoError(t, err)
+	assert.Len(t, usage.LastUsed, binaries)
+	assert.NoFileExists(t, usagePath+".lock")
+}
+
+func TestUpdateUsageStaleLock(t *testing.T) {
+	usagePath := filepath.Join(t.TempDir(), UsageFileName)
+	lockPath := usagePath + ".lock"
+	require.NoError(t, os.WriteFile(lockPath, nil, 0o600))
+	old := time.Now().Add(-2 * usageStaleLockAge)
+	require.NoError(t, os.Chtimes(lockPath, old, old))
+
+	require.NoError(t, UpdateUsage(usagePath, func(usage *Usage) bool {
+		usage.Touch("/tmp/kubectl1.30.0")
+		return true
+	}))
+	assert.NoFileExists(t, lockPath)
+}
+
+func TestFinderSetsLastUsed(t *testing.T) {
+	td, err := setupFilesystemTest()
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, teardownFilesystemTest(td))
+	}()
+
+	localBins := fakeKubectlBinaries(
+		td.FakeHome,
+		[]string{"1.28.3", "1.29.0"},
+		&localKubectlNamer{})
+	require.NoError(t, createFakeKubectlBinaries(localBins))
+
+	td.Finder.usagePath = filepath.Join(td.FakeHome, UsageFileName)
+	require.NoError(t, RecordUsage(td.Finder.usagePath, filepath.Dir(localBins[0].Path), localBins[0].Path))
+
+	bins, err := td.Finder.LocalKubectlBinaries()
+	require.NoError(t, err)
+	require.Len(t, bins, 2)
+	for _, b := range bins {
+		assert.Equal(t, OriginLocal, b.Origin)
+		assert.Equal(t, b.Path == localBins[0].Path, !b.LastUsed.IsZero(), "wrong LastUsed of %s", b.Path)
+	}
+}
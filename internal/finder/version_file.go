@@ -0,0 +1,106 @@
+package finder
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/blang/semver/v4"
+
+	"github.com/flavio/kuberlr/internal/common"
+)
+
+// VersionFileName is the name of the file used to pin the version of
+// kubectl used inside of a directory and all its children.
+const VersionFileName = ".kubectl-version"
+
+// VersionFile describes a `.kubectl-version` file. The file holds either
+// a full version (e.g. `1.28.4`) or a semver range (e.g. `>=1.27 <1.29`,
+// `1.28`).
+type VersionFile struct {
+	// Path of the file
+	Path string
+	// Constraint is the contents of the file
+	Constraint string
+	// Version is set when the file holds a full version
+	Version *semver.Version
+	// Range is set when the file holds a range of versions
+	Range *common.VersionRange
+}
+
+// FindVersionFile looks for a `.kubectl-version` file inside of the given
+// directory and all its parents. The boolean is false when no file is found.
+func FindVersionFile(dir string) (VersionFile, bool, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return VersionFile{}, false, err
+	}
+
+	for {
+		path := filepath.Join(dir, VersionFileName)
+		if _, err = os.Stat(path); err == nil {
+			versionFile, parseErr := ParseVersionFile(path)
+			return versionFile, true, parseErr
+		} else if !os.IsNotExist(err) {
+			return VersionFile{}, false, err
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return VersionFile{}, false, nil
+		}
+		dir = parent
+	}
+}
+
+// ParseVersionFile reads the given `.kubectl-version` file. Empty lines and
+// lines starting with `#` are ignored.
+func ParseVersionFile(path string) (VersionFile, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return VersionFile{}, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		versionFile, parseErr := parseVersionConstraint(line)
+		if parseErr != nil {
+			return VersionFile{}, fmt.Errorf("invalid contents of %s: %w", path, parseErr)
+		}
+		versionFile.Path = path
+		return versionFile, nil
+	}
+	if err = scanner.Err(); err != nil {
+		return VersionFile{}, err
+	}
+
+	return VersionFile{}, fmt.Errorf("%s doesn't specify any version", path)
+}
+
+func parseVersionConstraint(constraint string) (VersionFile, error) {
+	versionFile := VersionFile{Constraint: constraint}
+
+	// only full versions are handled as versions, `1.28` is
+	// handled like the `1.28.x` range
+	if version, err := semver.Parse(strings.TrimPrefix(constraint, "v")); err == nil {
+		versionFile.Version = &version
+		return versionFile, nil
+	}
+
+	versionRange, err := common.ParseVersionRange(constraint)
+	if err != nil {
+		return VersionFile{}, errors.New("neither a version nor a range of versions")
+	}
+	versionFile.Range = versionRange
+
+	return versionFile, nil
+}
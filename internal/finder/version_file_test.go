@@ -0,0 +1,101 @@
+package finder
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/blang/semver/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindVersionFileInParentDirectory(t *testing.T) {
+	root := t.TempDir()
+	nested := filepath.Join(root, "project", "deploy", "overlays")
+	require.NoError(t, os.MkdirAll(nested, 0o750))
+	require.NoError(t, os.WriteFile(
+		filepath.Join(root, "project", VersionFileName),
+		[]byte("# pinned for the production cluster\n\nv1.28.4\n"),
+		0o600))
+
+	versionFile, found, err := FindVersionFile(nested)
+	require.NoError(t, err)
+	require.True(t, found)
+	assert.Equal(t, filepath.Join(root, "project", VersionFileName), versionFile.Path)
+	require.NotNil(t, versionFile.Version)
+	assert.Equal(t, semver.MustParse("1.28.4"), *versionFile.Version)
+	assert.Nil(t, versionFile.Range)
+}
+
+func TestFindVersionFileNotFound(t *testing.T) {
+	_, found, err := FindVersionFile(t.TempDir())
+	require.NoError(t, err)
+	assert.False(t, found)
+}
+
+func TestParseVersionFile(t *testing.T) {
+	tests := []struct {
+		name          string
+		contents      string
+		expectsError  bool
+		expectedRange bool
+		inRange       []string
+		outOfRange    []string
+	}{
+		{
+			name:     "full version",
+			contents: "1.29.1",
+		},
+		{
+			name:          "minor version is a range",
+			contents:      "1.28",
+			expectedRange: true,
+			inRange:       []string{"1.28.0", "1.28.7"},
+			outOfRange:    []string{"1.29.0"},
+		},
+		{
+			name:          "range",
+			contents:      ">=1.27 <1.29",
+			expectedRange: true,
+			inRange:       []string{"1.27.2", "1.28.7"},
+			outOfRange:    []string{"1.26.0", "1.29.0"},
+		},
+		{
+			name:         "garbage",
+			contents:     "the latest one",
+			expectsError: true,
+		},
+		{
+			name:         "empty",
+			contents:     "# nothing here\n",
+			expectsError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), VersionFileName)
+			require.NoError(t, os.WriteFile(path, []byte(tt.contents), 0o600))
+
+			versionFile, err := ParseVersionFile(path)
+			if tt.expectsError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			if !tt.expectedRange {
+				require.NotNil(t, versionFile.Version)
+				return
+			}
+			require.NotNil(t, versionFile.Range)
+			for _, v := range tt.inRange {
+				assert.True(t, versionFile.Range.Contains(semver.MustParse(v)), "%s should be inside of %q", v, tt.contents)
+			}
+			for _, v := range tt.outOfRange {
+				assert.False(t, versionFile.Range.Contains(semver.MustParse(v)), "%s should be outside of %q", v, tt.contents)
+			}
+		})
+	}
+}
@@ -0,0 +1,218 @@
+package finder
+
+import (
+	"context"
+	"debug/buildinfo"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"github.com/blang/semver/v4"
+	"k8s.io/klog"
+
+	"github.com/flavio/kuberlr/internal/common"
+	"github.com/flavio/kuberlr/internal/osexec"
+)
+
+// VersionCacheFileName is the name of the file where kuberlr caches the
+// versions detected by inspecting the kubectl binaries.
+const VersionCacheFileName = "versions-cache.json"
+
+// kuberlrModulePath is the path of the go module of kuberlr, binaries
+// built from it are copies of kuberlr, not kubectl.
+const kuberlrModulePath = "github.com/flavio/kuberlr"
+
+// probeTimeout is how long `kubectl version` is allowed to run.
+const probeTimeout = 5 * time.Second
+
+var (
+	// kubectlNameRe matches the names of the binaries that could be kubectl:
+	// `kubectl`, `kubectl-1.28`, `kubectl_v1.28.4`, `kubectl1.28.4`,...
+	kubectlNameRe = regexp.MustCompile( //nolint: gochecknoglobals // regexps cannot be go constants
+		`^kubectl([-_.]?v?\d+(\.\d+){0,2}(-[0-9A-Za-z.-]+)?)?$`)
+	// gitVersionRe matches the version injected into kubectl via ldflags.
+	gitVersionRe = regexp.MustCompile( //nolint: gochecknoglobals // regexps cannot be go constants
+		`version\.gitVersion=(v?\d+\.\d+\.\d+[^\s'"]*)`)
+	errKuberlrBinary = errors.New("this is a copy of kuberlr") //nolint: gochecknoglobals // sentinel error
+)
+
+// probedVersion is an entry of the version cache.
+type probedVersion struct {
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"modTime"`
+	// Version is empty when the binary is not kubectl
+	Version string `json:"version"`
+}
+
+// versionProbe detects the version of kubectl binaries by inspecting them.
+// Results are cached on disk, keyed by path, size and modification time.
+type versionProbe struct {
+	cachePath string
+	cache     map[string]probedVersion
+	dirty     bool
+}
+
+// DefaultVersionCachePath returns the path to the file used to cache the
+// versions detected by inspecting the kubectl binaries.
+func DefaultVersionCachePath() string {
+	return filepath.Join(common.KuberlrDir(), VersionCacheFileName)
+}
+
+func newVersionProbe(cachePath string) *versionProbe {
+	probe := &versionProbe{
+		cachePath: cachePath,
+		cache:     map[string]probedVersion{},
+	}
+
+	data, err := os.ReadFile(cachePath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			klog.V(common.VerbosityOne).Infof("cannot read version cache %s: %v", cachePath, err)
+		}
+		return probe
+	}
+	if err = json.Unmarshal(data, &probe.cache); err != nil {
+		klog.V(common.VerbosityOne).Infof("ignoring corrupted version cache %s: %v", cachePath, err)
+		probe.cache = map[string]probedVersion{}
+	}
+
+	return probe
+}
+
+// LooksLikeKubectl returns true when the given file name could be the one
+// of a kubectl binary. This excludes kubectl plugins like `kubectl-krew`.
+func LooksLikeKubectl(filename string) bool {
+	return kubectlNameRe.MatchString(osexec.TrimExt(filename))
+}
+
+// IsKuberlrBinary returns true when the given file is a build of kuberlr,
+// like a copy of it named kubectl.
+func IsKuberlrBinary(path string) bool {
+	_, err := probeBuildInfo(path)
+	return errors.Is(err, errKuberlrBinary)
+}
+
+// Version returns the version of the kubectl binary at the given path. The
+// boolean is false when the version cannot be detected.
+func (p *versionProbe) Version(path string, info os.FileInfo) (semver.Version, bool) {
+	if cached, found := p.cache[path]; found &&
+		cached.Size == info.Size() && cached.ModTime.Equal(info.ModTime()) {
+		return parseProbedVersion(cached.Version)
+	}
+
+	version, err := probeBuildInfo(path)
+	if err != nil {
+		klog.V(common.VerbosityTwo).Infof("cannot find kubectl version of %s inside of its build information: %v", path, err)
+		if !errors.Is(err, errKuberlrBinary) {
+			version, err = probeVersionCommand(path)
+			if err != nil {
+				klog.V(common.VerbosityTwo).Infof("cannot find kubectl version of %s by running it: %v", path, err)
+			}
+		}
+	}
+
+	p.cache[path] = probedVersion{
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+		Version: version,
+	}
+	p.dirty = true
+
+	return parseProbedVersion(version)
+}
+
+// Forget drops the cached version of the given binary, it's meant to be
+// called once the binary has been removed.
+func (p *versionProbe) Forget(path string) {
+	if _, found := p.cache[path]; found {
+		delete(p.cache, path)
+		p.dirty = true
+	}
+}
+
+// Save writes the cache back to disk, if it has been changed.
+func (p *versionProbe) Save() {
+	if !p.dirty {
+		return
+	}
+
+	data, err := json.Marshal(p.cache)
+	if err == nil {
+		err = common.WriteFileAtomically(p.cachePath, data)
+	}
+	if err != nil {
+		klog.V(common.VerbosityOne).Infof("cannot write version cache %s: %v", p.cachePath, err)
+		return
+	}
+	p.dirty = false
+}
+
+func parseProbedVersion(version string) (semver.Version, bool) {
+	if version == "" {
+		return semver.Version{}, false
+	}
+
+	v, err := semver.ParseTolerant(version)
+	if err != nil {
+		return semver.Version{}, false
+	}
+
+	return common.KubernetesVersion(v), true
+}
+
+// probeBuildInfo reads the kubectl version injected via ldflags from the
+// build information embedded by the go compiler.
+func probeBuildInfo(path string) (string, error) {
+	info, err := buildinfo.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	if info.Path == kuberlrModulePath+"/cmd/kuberlr" || info.Main.Path == kuberlrModulePath {
+		return "", errKuberlrBinary
+	}
+
+	for _, setting := range info.Settings {
+		if setting.Key != "-ldflags" {
+			continue
+		}
+		if match := gitVersionRe.FindStringSubmatch(setting.Value); match != nil {
+			return match[1], nil
+		}
+	}
+
+	return "", errors.New("no version found inside of ldflags")
+}
+
+// probeVersionCommand runs `<bin> version --client -o json`.
+func probeVersionCommand(path string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), probeTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, path, "version", "--client", "-o", "json")
+	// in case the binary is a copy of kuberlr, prevent it from contacting
+	// the API server
+	cmd.Env = append(os.Environ(), PreventRecursiveInvocationEnvName+"=1")
+	out, err := cmd.Output()
+	if err != nil {
+		return "", err
+	}
+
+	var versionInfo struct {
+		ClientVersion struct {
+			GitVersion string `json:"gitVersion"`
+		} `json:"clientVersion"`
+	}
+	if err = json.Unmarshal(out, &versionInfo); err != nil {
+		return "", fmt.Errorf("cannot parse output of `%s version`: %w", path, err)
+	}
+	if versionInfo.ClientVersion.GitVersion == "" {
+		return "", fmt.Errorf("`%s version` did not report the client version", path)
+	}
+
+	return versionInfo.ClientVersion.GitVersion, nil
+}
@@ -0,0 +1,131 @@
+package finder
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/blang/semver/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeFakeKubectlScript creates a shell script that behaves like
+// `kubectl version --client -o json`.
+func writeFakeKubectlScript(t *testing.T, path, gitVersion string) {
+	t.Helper()
+
+	if runtime.GOOS == "windows" {
+		t.Skip("shell scripts cannot be executed on windows")
+	}
+
+	script := fmt.Sprintf("#!/bin/sh\necho '{\"clientVersion\": {\"gitVersion\": \"%s\"}}'\n", gitVersion)
+	require.NoError(t, os.WriteFile(path, []byte(script), 0o700)) //nolint: gosec // the file must be executable
+}
+
+func TestLooksLikeKubectl(t *testing.T) {
+	for _, name := range []string{"kubectl", "kubectl-1.28", "kubectl_v1.28.4", "kubectl1.28.4", "kubectl-1.31.0-rc.1"} {
+		assert.True(t, LooksLikeKubectl(name), name)
+	}
+	for _, name := range []string{"kubectl-krew", "kubectl-ns", "kubectx", "kuberlr"} {
+		assert.False(t, LooksLikeKubectl(name), name)
+	}
+}
+
+func TestVersionProbeRunsBinaryAndCachesResult(t *testing.T) {
+	dir := t.TempDir()
+	binPath := filepath.Join(dir, "kubectl")
+	writeFakeKubectlScript(t, binPath, "v1.28.4+k3s1")
+
+	cachePath := filepath.Join(dir, "cache", VersionCacheFileName)
+	probe := newVersionProbe(cachePath)
+
+	info, err := os.Stat(binPath)
+	require.NoError(t, err)
+	version, found := probe.Version(binPath, info)
+	require.True(t, found)
+	assert.Equal(t, semver.MustParse("1.28.4"), version)
+	probe.Save()
+
+	// replace the binary, keeping the same size and modification time:
+	// the cached version must be used
+	writeFakeKubectlScript(t, binPath, "v1.29.9+k3s1")
+	require.NoError(t, os.Chtimes(binPath, info.ModTime(), info.ModTime()))
+	info, err = os.Stat(binPath)
+	require.NoError(t, err)
+
+	version, found = newVersionProbe(cachePath).Version(binPath, info)
+	require.True(t, found)
+	assert.Equal(t, semver.MustParse("1.28.4"), version)
+}
+
+func TestVersionProbeNotKubectl(t *testing.T) {
+	dir := t.TempDir()
+	binPath := filepath.Join(dir, "kubectl")
+	require.NoError(t, os.WriteFile(binPath, []byte("not a binary"), 0o600))
+
+	info, err := os.Stat(binPath)
+	require.NoError(t, err)
+	_, found := newVersionProbe(filepath.Join(dir, VersionCacheFileName)).Version(binPath, info)
+	assert.False(t, found)
+}
+
+func TestFinderDetectsVersionOfArbitrarilyNamedBinaries(t *testing.T) {
+	td, err := setupFilesystemTest()
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, teardownFilesystemTest(td))
+	}()
+	td.Finder.probe = newVersionProbe(filepath.Join(td.FakeHome, VersionCacheFileName))
+
+	writeFakeKubectlScript(t, filepath.Join(td.FakeSysBinPath, "kubectl"), "v1.30.2")
+	// mislabelled binary
+	writeFakeKubectlScript(t, filepath.Join(td.FakeSysBinPath, "kubectl1.27"), "v1.28.3")
+	// kubectl plugins are never executed
+	writeFakeKubectlScript(t, filepath.Join(td.FakeSysBinPath, "kubectl-krew"), "v1.99.0")
+
+	bins, err := td.Finder.SystemKubectlBinaries()
+	require.NoError(t, err)
+
+	versions := map[string]string{}
+	for _, b := range bins {
+		versions[filepath.Base(b.Path)] = b.Version.String()
+	}
+	assert.Equal(t, map[string]string{
+		"kubectl":     "1.30.2",
+		"kubectl1.27": "1.28.3",
+	}, versions)
+}
+
+func TestFinderKubectlVersion(t *testing.T) {
+	td, err := setupFilesystemTest()
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, teardownFilesystemTest(td))
+	}()
+	td.Finder.probe = newVersionProbe(filepath.Join(td.FakeHome, VersionCacheFileName))
+
+	// outside of the directories handled by the finder
+	binPath := filepath.Join(td.FakeHome, "kubectl")
+	writeFakeKubectlScript(t, binPath, "v1.29.1")
+
+	version, found := td.Finder.KubectlVersion(binPath)
+	require.True(t, found)
+	assert.Equal(t, semver.MustParse("1.29.1"), version)
+
+	_, found = td.Finder.KubectlVersion(filepath.Join(td.FakeHome, "missing"))
+	assert.False(t, found)
+}
+
+func TestIsKuberlrBinary(t *testing.T) {
+	// the test binary is built from the kuberlr module too
+	self, err := os.Executable()
+	require.NoError(t, err)
+	assert.True(t, IsKuberlrBinary(self))
+
+	binPath := filepath.Join(t.TempDir(), "kubectl")
+	writeFakeKubectlScript(t, binPath, "v1.29.1")
+	assert.False(t, IsKuberlrBinary(binPath))
+}
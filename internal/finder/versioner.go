@@ -17,15 +17,17 @@ import (
 
 type downloadHelper interface {
 	GetKubectlBinary(version semver.Version, destination string) error
+	LatestVersionInRange(versionRange *common.VersionRange) (semver.Version, error)
 	UpstreamStableVersion() (semver.Version, error)
 }
 
 type kubeAPIHelper interface {
-	Version(timeout int64) (semver.Version, error)
+	Version(timeout int64, args []string) (semver.Version, error)
 }
 
 type iFinder interface {
 	AllKubectlBinaries(reverseSort bool) KubectlBinaries
+	EnforceMaxCacheSize(maxSize int64, keepPath string) (KubectlBinaries, error)
 }
 
 // Versioner is used to manage the local kubectl binaries used by kuberlr.
@@ -33,7 +35,12 @@ type Versioner struct {
 	kFinder                           iFinder
 	downloader                        downloadHelper
 	apiServer                         kubeAPIHelper
+	skewPolicy                        SkewPolicy
+	rankingPreferences                RankingPreferences
 	preventRecursiveInvocationEnvName string
+	// maxCacheSize is the maximum size, in bytes, of the kubectl binaries
+	// downloaded by kuberlr. Zero means no limit.
+	maxCacheSize int64
 }
 
 // NewVersioner is an helper function that creates a new Versioner instance.
@@ -42,16 +49,115 @@ func NewVersioner(f iFinder) *Versioner {
 		kFinder:                           f,
 		downloader:                        &downloader.Downloder{},
 		apiServer:                         &kubehelper.KubeAPI{},
+		skewPolicy:                        UpstreamSkewPolicy(),
 		preventRecursiveInvocationEnvName: PreventRecursiveInvocationEnvName,
 	}
 }
 
+// SetKubeMirrorURL changes the URL of the mirror used to download the
+// missing kubectl binaries.
+func (v *Versioner) SetKubeMirrorURL(url string) {
+	v.downloader = &downloader.Downloder{KubeMirrorURL: url}
+}
+
+// SetServerVersionCache makes the versioner cache the version of the API
+// servers inside of the given cache.
+func (v *Versioner) SetServerVersionCache(cache *kubehelper.ServerVersionCache) {
+	v.apiServer = &kubehelper.KubeAPI{VersionCache: cache}
+}
+
+// SetMaxCacheSize limits the total size, in bytes, of the kubectl binaries
+// downloaded by kuberlr. The least recently used binaries are removed after
+// each download exceeding the limit. Zero means no limit.
+func (v *Versioner) SetMaxCacheSize(bytes int64) {
+	v.maxCacheSize = bytes
+}
+
+// SetSkewPolicy changes the policy used to decide which kubectl versions
+// are compatible with the API server.
+func (v *Versioner) SetSkewPolicy(policy SkewPolicy) {
+	v.skewPolicy = policy
+}
+
+// SkewPolicy returns the policy used to decide which kubectl versions are
+// compatible with the API server.
+func (v *Versioner) SkewPolicy() SkewPolicy {
+	if v.skewPolicy.Name == "" {
+		return UpstreamSkewPolicy()
+	}
+	return v.skewPolicy
+}
+
+// SetRankingPreferences changes the criteria used to pick the best kubectl
+// binary among the compatible ones. An empty list makes the most recent
+// compatible binary win.
+func (v *Versioner) SetRankingPreferences(preferences RankingPreferences) {
+	v.rankingPreferences = preferences
+}
+
+// RankingPreferences returns the criteria used to pick the best kubectl
+// binary among the compatible ones.
+func (v *Versioner) RankingPreferences() RankingPreferences {
+	if v.rankingPreferences == nil {
+		return DefaultRankingPreferences()
+	}
+	return v.rankingPreferences
+}
+
+// RankCompatibleKubectl returns the kubectl binaries compatible with the
+// given API server version, best candidate first.
+func (v *Versioner) RankCompatibleKubectl(version semver.Version) RankedKubectlBinaries {
+	return RankKubectlBinaries(
+		version,
+		v.kFinder.AllKubectlBinaries(true),
+		v.SkewPolicy(),
+		v.RankingPreferences())
+}
+
 const PreventRecursiveInvocationEnvName = "KUBERLR_RESOLVING_VERSION"
 
+// errRecursiveInvocation is returned when kuberlr is invoked by client-go
+// while it's looking for the version of the API server.
+var errRecursiveInvocation = errors.New("kuberlr has been invoked while resolving the kubernetes version, the API server is not contacted")
+
 // KubectlVersionToUse returns the kubectl version to be used to interact with
-// the remote server. The method takes into account different failure scenarios
-// and acts accordingly.
-func (v *Versioner) KubectlVersionToUse(timeout int64) (semver.Version, error) {
+// the remote server. The `args` parameter holds the arguments given to kubectl,
+// they are used to find the API server to contact. The method takes into
+// account different failure scenarios and acts accordingly.
+func (v *Versioner) KubectlVersionToUse(timeout int64, args []string) (semver.Version, error) {
+	version, _, err := v.ResolveKubectlVersion(timeout, args)
+	return version, err
+}
+
+// ResolveKubectlVersion is like KubectlVersionToUse, it also returns the
+// error that prevented from finding the version of the API server. When
+// this error is not nil, the returned version is the fallback one.
+func (v *Versioner) ResolveKubectlVersion(timeout int64, args []string) (semver.Version, error, error) {
+	if v.recursiveInvocation() {
+		klog.V(common.VerbosityTwo).Info("client-go invoked kubectl to authenticate. Preventing kuberlr endless recursion loop.")
+		version, err := v.FallbackKubectlVersion()
+		return version, errRecursiveInvocation, err
+	}
+
+	version, serverErr := v.ServerVersion(timeout, args)
+	if serverErr != nil {
+		if isUnreachable(serverErr) {
+			// the remote server is unreachable, let's get
+			// the latest version of kubectl that is available on the system
+			klog.V(common.VerbosityTwo).Info("Remote kubernetes server unreachable")
+		} else {
+			klog.V(common.VerbosityOne).Info(serverErr)
+		}
+		version, err := v.FallbackKubectlVersion()
+		return version, serverErr, err
+	}
+	return version, nil, nil
+}
+
+// ServerVersion returns the version of the API server kubectl is going to
+// contact when invoked with the given arguments. Unlike KubectlVersionToUse,
+// errors are returned as they are.
+func (v *Versioner) ServerVersion(timeout int64, args []string) (semver.Version, error) {
 	// We use Kubernetes client-go to interact with the remote server to obtain its version.
 	// Depending on the cluster configuration, the client-go library might shell out and invoke
 	// the kubectl binary to authenticate to the server.
@@ -59,11 +165,8 @@ func (v *Versioner) KubectlVersionToUse(timeout int64) (semver.Version, error) {
 	// where kuberlr calls kubectl that in turn calls kuberlr again.
 	//
 	// To avoid this, we set an environment variable to signal that we are currently resolving the kubernetes version.
-
-	_, recursiveInvocationDetected := os.LookupEnv(v.preventRecursiveInvocationEnvName)
-	if recursiveInvocationDetected {
-		klog.V(common.VerbosityTwo).Info("client-go invoked kubectl to authenticate. Preventing kuberlr endless recursion loop.")
-		return v.mostRecentKubectlVersionAvailableOrLatestFromUpstream()
+	if v.recursiveInvocation() {
+		return semver.Version{}, errRecursiveInvocation
 	}
 
 	if err := os.Setenv(v.preventRecursiveInvocationEnvName, "1"); err != nil {
@@ -71,24 +174,19 @@ func (v *Versioner) KubectlVersionToUse(timeout int64) (semver.Version, error) {
 	}
 	defer os.Unsetenv(v.preventRecursiveInvocationEnvName)
 
-	version, err := v.apiServer.Version(timeout)
-	if err != nil {
-		if isUnreachable(err) {
-			// the remote server is unreachable, let's get
-			// the latest version of kubectl that is available on the system
-			klog.V(common.VerbosityTwo).Info("Remote kubernetes server unreachable")
-		} else {
-			klog.V(common.VerbosityOne).Info(err)
-		}
-		return v.mostRecentKubectlVersionAvailableOrLatestFromUpstream()
-	}
-	return version, err
+	return v.apiServer.Version(timeout, args)
 }
 
-// mostRecentKubectlVersionAvailableOrLatestFromUpstream returns the most recent version of kubectl
-// available on the system. If no kubectl binary is found, it will download the
-// latest stable version from the upstream mirror.
-func (v *Versioner) mostRecentKubectlVersionAvailableOrLatestFromUpstream() (semver.Version, error) {
+func (v *Versioner) recursiveInvocation() bool {
+	_, recursiveInvocationDetected := os.LookupEnv(v.preventRecursiveInvocationEnvName)
+	return recursiveInvocationDetected
+}
+
+// FallbackKubectlVersion returns the version used when the one of the API
+// server cannot be found: the most recent version of kubectl available on
+// the system or, when no kubectl binary is found, the latest stable version
+// from the upstream mirror.
+func (v *Versioner) FallbackKubectlVersion() (semver.Version, error) {
 	bins := v.kFinder.AllKubectlBinaries(true)
 	if kubectl, err := mostRecentKubectlAvailable(bins); err == nil {
 		return kubectl.Version, nil
@@ -98,43 +196,48 @@ func (v *Versioner) mostRecentKubectlVersionAvailableOrLatestFromUpstream() (sem
 	return v.downloader.UpstreamStableVersion()
 }
 
+// FindCompatibleKubectl returns the best kubectl binary available on the
+// system that is compatible with the given API server version. Nothing is
+// downloaded.
+func (v *Versioner) FindCompatibleKubectl(version semver.Version) (KubectlBinary, error) {
+	bins := v.kFinder.AllKubectlBinaries(true)
+	return findCompatibleKubectl(version, bins, v.SkewPolicy(), v.RankingPreferences())
+}
+
 // EnsureCompatibleKubectlAvailable ensures the kubectl binary with the specified
 // version is available on the system. It will return the full path to the
 // binary.
 func (v *Versioner) EnsureCompatibleKubectlAvailable(version semver.Version, allowDownload bool, useLatestIfNoCompatible bool) (string, error) {
-	bins := v.kFinder.AllKubectlBinaries(true)
-	kubectl, err := findCompatibleKubectl(version, bins)
-	if err == nil {
-		return kubectl.Path, nil
-	}
-
-	if !allowDownload {
-		if useLatestIfNoCompatible {
-			all := v.kFinder.AllKubectlBinaries(true) // newest-first
-			if len(all) > 0 {
-				return all[0].Path, nil
-			}
-		}
-		return "", errors.New("the right kubectl is missing, binary downloads from kubernetes' upstream mirror are disabled")
-	}
+	return v.Execute(v.PlanCompatible(version, allowDownload, useLatestIfNoCompatible))
+}
 
-	klog.Infof("Right kubectl missing, downloading version %s", version.String())
+// EnsureKubectlInRangeAvailable ensures a kubectl binary whose version is
+// inside of the given range is available on the system. When no such binary
+// is found, the most recent stable release inside of the range is
+// downloaded. Like with EnsureCompatibleKubectlAvailable, the newest local
+// binary is used when useLatestIfNoCompatible is set and the download is
+// not possible. It will return the full path to the binary.
+func (v *Versioner) EnsureKubectlInRangeAvailable(versionRange *common.VersionRange, allowDownload bool, useLatestIfNoCompatible bool) (string, error) {
+	return v.Execute(v.PlanInRange(versionRange, allowDownload, useLatestIfNoCompatible))
+}
 
-	// download the right kubectl to the local cache
+// downloadKubectl downloads the given version of kubectl to the local cache.
+// It returns the full path to the binary.
+func (v *Versioner) downloadKubectl(version semver.Version) (string, error) {
 	filename := filepath.Join(
 		common.LocalDownloadDir(),
 		common.BuildKubectlNameForLocalBin(version))
 
-	if err = v.downloader.GetKubectlBinary(version, filename); err != nil {
-		if useLatestIfNoCompatible {
-			all := v.kFinder.AllKubectlBinaries(true) // newest-first
-			if len(all) > 0 {
-				klog.Infof("download failed (%v); falling back to newest local kubectl %s at %s",
-					err, all[0].Version, all[0].Path)
-				return all[0].Path, nil
-			}
+	if err := v.downloader.GetKubectlBinary(version, filename); err != nil {
+		return "", err
+	}
+
+	if v.maxCacheSize > 0 {
+		// the binary is already available, failing to evict the old
+		// ones must not prevent its usage
+		if _, err := v.kFinder.EnforceMaxCacheSize(v.maxCacheSize, filename); err != nil {
+			klog.V(common.VerbosityOne).Infof("cannot enforce MaxCacheSize: %v", err)
 		}
-		return "", fmt.Errorf("failed to download compatible kubectl: %w", err)
 	}
 
 	return filename, nil
@@ -145,30 +248,22 @@ func isUnreachable(err error) bool {
 	return os.IsTimeout(err) || errors.As(err, &e)
 }
 
-// findCompatibleKubectl returns a kubectl binary compatible with the
-// version given via the `requestedVersion` parameter.
+// findCompatibleKubectl returns the best kubectl binary compatible with the
+// version given via the `requestedVersion` parameter, according to the
+// given skew policy and ranking preferences.
 // Important: the `bins` parameter must be sorted in descending order.
-func findCompatibleKubectl(requestedVersion semver.Version, bins KubectlBinaries) (KubectlBinary, error) {
-	if len(bins) == 0 {
-		return KubectlBinary{}, &common.NoVersionFoundError{}
-	}
-
-	lowerBound := lowerBoundVersion(requestedVersion)
-	upperBound := upperBoundVersion(requestedVersion)
-	rangeRule := fmt.Sprintf(">=%s <%s", lowerBound.String(), upperBound.String())
-
-	validRange, err := semver.ParseRange(rangeRule)
-	if err != nil {
-		return KubectlBinary{}, err
-	}
-
-	for _, b := range bins {
-		if validRange(b.Version) {
-			return b, nil
-		}
+func findCompatibleKubectl(
+	requestedVersion semver.Version,
+	bins KubectlBinaries,
+	policy SkewPolicy,
+	preferences RankingPreferences,
+) (KubectlBinary, error) {
+	ranked := RankKubectlBinaries(requestedVersion, bins, policy, preferences)
+	if len(ranked) == 0 {
+		return KubectlBinary{}, &common.NoVersionFoundError{Range: policy.RangeRule(requestedVersion)}
 	}
 
-	return KubectlBinary{}, &common.NoVersionFoundError{}
+	return ranked[0].KubectlBinary, nil
 }
 
 // mostRecentKubectlAvailable returns the most recent version of
@@ -181,23 +276,3 @@ func mostRecentKubectlAvailable(bins KubectlBinaries) (KubectlBinary, error) {
 
 	return bins[0], nil
 }
-
-func lowerBoundVersion(v semver.Version) semver.Version {
-	res := v
-
-	res.Patch = 0
-	if v.Minor > 0 {
-		res.Minor = v.Minor - 1
-	}
-
-	return res
-}
-
-func upperBoundVersion(v semver.Version) semver.Version {
-	//nolint: mnd // we are setting the patch version to 0
-	return semver.Version{
-		Major: v.Major,
-		Minor: v.Minor + 2,
-		Patch: 0,
-	}
-}
@@ -158,8 +158,9 @@ func TestKubectlVersionToUseTimeoutWhenTalkingWithKubernetesAPIServer(t *testing
 			}
 
 			expectedTimeout := int64(1)
+			kubectlArgs := []string{"--context", "dev", "get", "pods"}
 			apiMock := NewMockkubeAPIHelper(t)
-			apiMock.EXPECT().Version(expectedTimeout).Return(semver.Version{}, &mockTimeoutError{})
+			apiMock.EXPECT().Version(expectedTimeout, kubectlArgs).Return(semver.Version{}, &mockTimeoutError{})
 
 			versioner := Versioner{
 				kFinder:                           finderMock,
@@ -168,7 +169,7 @@ func TestKubectlVersionToUseTimeoutWhenTalkingWithKubernetesAPIServer(t *testing
 				preventRecursiveInvocationEnvName: fmt.Sprintf("KUBERLR_RESOLVING_VERSION_%d", rand.Intn(100)),
 			}
 
-			actual, err := versioner.KubectlVersionToUse(expectedTimeout)
+			actual, err := versioner.KubectlVersionToUse(expectedTimeout, kubectlArgs)
 			require.NoError(t, err)
 			assert.Equal(t, expectedVersion, actual, "got %s instead of %s", actual, expectedVersion)
 		})
@@ -227,9 +228,10 @@ func TestKubectlVersionToUseSetsInfiniteRecursionPrevention(t *testing.T) {
 			downloaderMock := NewMockdownloadHelper(t)
 
 			expectedTimeout := int64(1)
+			kubectlArgs := []string{"--context", "dev", "get", "pods"}
 			apiMock := NewMockkubeAPIHelper(t)
 			if !tt.recursionHappening {
-				apiMock.EXPECT().Version(expectedTimeout).Return(tt.kubeAPIServerVersion, nil)
+				apiMock.EXPECT().Version(expectedTimeout, kubectlArgs).Return(tt.kubeAPIServerVersion, nil)
 			}
 
 			versioner := Versioner{
@@ -239,7 +241,7 @@ func TestKubectlVersionToUseSetsInfiniteRecursionPrevention(t *testing.T) {
 				preventRecursiveInvocationEnvName: preventRecursiveInvocationEnvName,
 			}
 
-			actual, err := versioner.KubectlVersionToUse(expectedTimeout)
+			actual, err := versioner.KubectlVersionToUse(expectedTimeout, kubectlArgs)
 			require.NoError(t, err)
 			assert.Equal(t, expectedVersion, actual, "got %s instead of %s", actual, expectedVersion)
 		})
@@ -278,7 +280,7 @@ func TestFindCompatibleKubectl(t *testing.T) {
 			expectedVersion:  semver.MustParse("2.1.3"),
 		},
 		{
-			name: "most recent version",
+			name: "exact minor version preferred over most recent version",
 			kubectlAvailableVersions: []string{
 				"2.1.3",
 				"1.5.3",
@@ -286,6 +288,17 @@ func TestFindCompatibleKubectl(t *testing.T) {
 				"1.1.3",
 			},
 			requestedVersion: semver.MustParse("1.4.0"),
+			expectedVersion:  semver.MustParse("1.4.2"),
+		},
+		{
+			name: "most recent version when no exact minor version is available",
+			kubectlAvailableVersions: []string{
+				"2.1.3",
+				"1.5.3",
+				"1.3.2",
+				"1.1.3",
+			},
+			requestedVersion: semver.MustParse("1.4.0"),
 			expectedVersion:  semver.MustParse("1.5.3"),
 		},
 		{
@@ -316,7 +329,7 @@ func TestFindCompatibleKubectl(t *testing.T) {
 				Path:    fmt.Sprintf("path/to/kubectl-%s", tt.expectedVersion),
 			}
 
-			actual, err := findCompatibleKubectl(tt.requestedVersion, kubectlBins)
+			actual, err := findCompatibleKubectl(tt.requestedVersion, kubectlBins, UpstreamSkewPolicy(), DefaultRankingPreferences())
 			if tt.expectedVersion.EQ(noVersionExpected) {
 				assert.Error(t, err)
 				isNoVersionFound := func() bool {
@@ -411,3 +424,136 @@ func TestEnsureCompatibleKubectlAvailable_DownloadFails_FallbackToNewest_WhenOpt
 	require.NoError(t, err)
 	require.Equal(t, "path/to/kubectl-1.30.1", got)
 }
+
+func TestEnsureKubectlInRangeAvailable(t *testing.T) {
+	kubectlBins := KubectlBinaries{
+		{Version: semver.MustParse("1.30.1"), Path: "path/to/kubectl-1.30.1"},
+		{Version: semver.MustParse("1.28.7"), Path: "path/to/kubectl-1.28.7"},
+		{Version: semver.MustParse("1.28.2"), Path: "path/to/kubectl-1.28.2"},
+	}
+
+	tests := []struct {
+		name                    string
+		versionRange            string
+		downloadAllowed         bool
+		useLatestIfNoCompatible bool
+		latestInRange           string
+		latestInRangeErr        error
+		downloadErr             error
+		expectedPath            string
+		expectsDownload         bool
+		expectsError            bool
+	}{
+		{
+			name:            "newest binary inside of the range is used",
+			versionRange:    "1.28",
+			downloadAllowed: true,
+			expectedPath:    "path/to/kubectl-1.28.7",
+		},
+		{
+			name:            "most recent release inside of the range is downloaded",
+			versionRange:    ">=1.31",
+			downloadAllowed: true,
+			latestInRange:   "1.31.2",
+			expectsDownload: true,
+		},
+		{
+			name:            "older minor version is downloaded",
+			versionRange:    "1.29",
+			downloadAllowed: true,
+			latestInRange:   "1.29.10",
+			expectsDownload: true,
+		},
+		{
+			name:             "no release inside of the range",
+			versionRange:     "1.29",
+			downloadAllowed:  true,
+			latestInRangeErr: errors.New("no stable release inside of the requested range"),
+			expectsError:     true,
+		},
+		{
+			name:                    "download failure falls back to the newest binary",
+			versionRange:            "1.29",
+			downloadAllowed:         true,
+			useLatestIfNoCompatible: true,
+			latestInRange:           "1.29.10",
+			downloadErr:             errors.New("network down"),
+			expectsDownload:         true,
+			expectedPath:            "path/to/kubectl-1.30.1",
+		},
+		{
+			name:            "downloads are not allowed",
+			versionRange:    "1.29",
+			downloadAllowed: false,
+			expectsError:    true,
+		},
+		{
+			name:                    "downloads are not allowed, newest binary is used",
+			versionRange:            "1.29",
+			downloadAllowed:         false,
+			useLatestIfNoCompatible: true,
+			expectedPath:            "path/to/kubectl-1.30.1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			versionRange, err := common.ParseVersionRange(tt.versionRange)
+			require.NoError(t, err)
+
+			finderMock := NewMockiFinder(t)
+			finderMock.EXPECT().AllKubectlBinaries(true).Return(kubectlBins)
+
+			downloaderMock := NewMockdownloadHelper(t)
+			if tt.latestInRange != "" || tt.latestInRangeErr != nil {
+				version := semver.Version{}
+				if tt.latestInRange != "" {
+					version = semver.MustParse(tt.latestInRange)
+				}
+				downloaderMock.EXPECT().LatestVersionInRange(mock.Anything).Return(version, tt.latestInRangeErr)
+			}
+			if tt.expectsDownload {
+				downloaderMock.EXPECT().GetKubectlBinary(semver.MustParse(tt.latestInRange), mock.AnythingOfType("string")).Return(tt.downloadErr)
+			}
+
+			versioner := Versioner{
+				kFinder:    finderMock,
+				downloader: downloaderMock,
+			}
+
+			actual, err := versioner.EnsureKubectlInRangeAvailable(versionRange, tt.downloadAllowed, tt.useLatestIfNoCompatible)
+			if tt.expectsError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			if tt.expectedPath != "" {
+				assert.Equal(t, tt.expectedPath, actual)
+			}
+		})
+	}
+}
+
+func TestFindCompatibleKubectlDoesNotDownload(t *testing.T) {
+	t.Parallel()
+
+	kubectlBins := KubectlBinaries{
+		{Version: semver.MustParse("1.30.1"), Path: "/bin/kubectl-1.30.1"},
+		{Version: semver.MustParse("1.28.6"), Path: "/bin/kubectl-1.28.6"},
+	}
+
+	finderMock := NewMockiFinder(t)
+	finderMock.EXPECT().AllKubectlBinaries(true).Return(kubectlBins)
+	finderMock.EXPECT().AllKubectlBinaries(true).Return(kubectlBins)
+
+	// the downloader mock fails the test when it's used
+	v := &Versioner{kFinder: finderMock, downloader: NewMockdownloadHelper(t)}
+
+	kubectl, err := v.FindCompatibleKubectl(semver.MustParse("1.28.2"))
+	require.NoError(t, err)
+	assert.Equal(t, "/bin/kubectl-1.28.6", kubectl.Path)
+
+	_, err = v.FindCompatibleKubectl(semver.MustParse("1.24.0"))
+	var noVersionErr *common.NoVersionFoundError
+	assert.ErrorAs(t, err, &noVersionErr)
+}
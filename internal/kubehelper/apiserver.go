@@ -1,16 +1,67 @@
 package kubehelper
 
 import (
+	"errors"
+	"net/url"
+	"os"
+
 	"github.com/blang/semver/v4"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/klog"
+
+	"github.com/flavio/kuberlr/internal/common"
 )
 
 // KubeAPI helps interactions with kubernetes API server.
 type KubeAPI struct {
+	// VersionCache, when set and not disabled, is used to avoid contacting
+	// the API server and to provide the last known version when it cannot
+	// be reached.
+	VersionCache *ServerVersionCache
+}
+
+// Version returns the version of the remote kubernetes API server kubectl
+// is going to contact when invoked with the given arguments. Vendor
+// specific suffixes are dropped, while alpha, beta and release candidate
+// identifiers are kept.
+func (k *KubeAPI) Version(timeout int64, args []string) (semver.Version, error) {
+	restConfig, err := createRestConfig(timeout, args)
+	if err != nil {
+		return semver.Version{}, err
+	}
+	if k.VersionCache.Disabled() {
+		return serverVersion(restConfig)
+	}
+
+	fingerprint := caFingerprint(restConfig)
+	cached, found, fresh := k.VersionCache.Lookup(restConfig.Host, fingerprint)
+	if found && fresh {
+		klog.V(common.VerbosityTwo).Infof("using cached version %s of %s", cached, restConfig.Host)
+		return cached, nil
+	}
+
+	version, err := serverVersion(restConfig)
+	if err != nil {
+		if found {
+			klog.V(common.VerbosityOne).Infof(
+				"cannot get version of %s, using last known version %s: %v",
+				restConfig.Host, cached, err)
+			return cached, nil
+		}
+		return semver.Version{}, err
+	}
+
+	if err = k.VersionCache.Store(restConfig.Host, fingerprint, version); err != nil {
+		klog.V(common.VerbosityOne).Infof("cannot cache version of %s: %v", restConfig.Host, err)
+	}
+
+	return version, nil
 }
 
-// Version returns the version of the remote kubernetes API server.
-func (k *KubeAPI) Version(timeout int64) (semver.Version, error) {
-	client, err := createKubeClient(timeout)
+func serverVersion(restConfig *rest.Config) (semver.Version, error) {
+	client, err := kubernetes.NewForConfig(restConfig)
 	if err != nil {
 		return semver.Version{}, err
 	}
@@ -19,5 +70,54 @@ func (k *KubeAPI) Version(timeout int64) (semver.Version, error) {
 	if err != nil {
 		return semver.Version{}, err
 	}
-	return semver.ParseTolerant(v.GitVersion)
+	version, err := semver.ParseTolerant(v.GitVersion)
+	if err != nil {
+		return semver.Version{}, err
+	}
+	return common.KubernetesVersion(version), nil
+}
+
+// CachedVersion returns the last known version of the API server kubectl is
+// going to contact when invoked with the given arguments, even when the
+// cache entry has expired. The API server is never contacted. The boolean
+// is false when the version of the API server is unknown, or when the cache
+// is disabled.
+func (k *KubeAPI) CachedVersion(args []string) (semver.Version, bool, error) {
+	if k.VersionCache.Disabled() {
+		return semver.Version{}, false, nil
+	}
+
+	restConfig, err := createRestConfig(0, args)
+	if err != nil {
+		return semver.Version{}, false, err
+	}
+
+	cached, found, _ := k.VersionCache.Lookup(restConfig.Host, caFingerprint(restConfig))
+	return cached, found, nil
+}
+
+const (
+	// ReasonUnreachable means the API server cannot be reached
+	ReasonUnreachable = "unreachable"
+	// ReasonUnauthorized means the API server rejected the credentials
+	ReasonUnauthorized = "unauthorized"
+	// ReasonForbidden means the user cannot read the version of the API server
+	ReasonForbidden = "forbidden"
+	// ReasonError is used for all the other errors
+	ReasonError = "error"
+)
+
+// ErrorReason summarizes why the version of an API server cannot be found.
+func ErrorReason(err error) string {
+	var urlErr *url.Error
+	switch {
+	case apierrors.IsForbidden(err):
+		return ReasonForbidden
+	case apierrors.IsUnauthorized(err):
+		return ReasonUnauthorized
+	case os.IsTimeout(err), errors.As(err, &urlErr):
+		return ReasonUnreachable
+	default:
+		return ReasonError
+	}
 }
@@ -0,0 +1,23 @@
+package kubehelper
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestErrorReason(t *testing.T) {
+	unreachable := &url.Error{Op: "Get", URL: "https://prod:6443/version", Err: errors.New("connection refused")}
+
+	assert.Equal(t, ReasonUnreachable, ErrorReason(unreachable))
+	assert.Equal(t, ReasonUnreachable, ErrorReason(fmt.Errorf("wrapped: %w", unreachable)))
+	assert.Equal(t, ReasonForbidden, ErrorReason(
+		apierrors.NewForbidden(schema.GroupResource{}, "version", errors.New("denied"))))
+	assert.Equal(t, ReasonUnauthorized, ErrorReason(apierrors.NewUnauthorized("invalid token")))
+	assert.Equal(t, ReasonError, ErrorReason(errors.New("invalid kubeconfig")))
+}
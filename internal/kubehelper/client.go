@@ -1,43 +1,35 @@
 package kubehelper
 
 import (
-	"os"
-	"strings"
+	"io"
+	"slices"
 	"time"
 
-	"k8s.io/client-go/kubernetes"
+	"github.com/spf13/pflag"
+	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
-)
+	"k8s.io/klog"
 
-func createKubeClient(timeout int64) (*kubernetes.Clientset, error) {
-	var cliKubeconfig string
-	var cliKubecontext string
+	"github.com/flavio/kuberlr/internal/common"
+)
 
-	// If there are mutiple occurrences of options supplied to kubectl, the
-	// last one takes precedence. The continue statements below ensures kuberlr
-	// preserves this behaviour
-	for index := 1; index < len(os.Args); index++ {
-		if index+1 < len(os.Args) && os.Args[index] == "--context" {
-			cliKubecontext = os.Args[index+1]
-			continue
-		}
-		if strings.HasPrefix(os.Args[index], "--context=") {
-			cliKubecontext = strings.TrimPrefix(os.Args[index], "--context=")
-			continue
-		}
-		if index+1 < len(os.Args) && os.Args[index] == "--kubeconfig" {
-			cliKubeconfig = os.Args[index+1]
-			continue
-		}
-		if strings.HasPrefix(os.Args[index], "--kubeconfig=") {
-			cliKubeconfig = strings.TrimPrefix(os.Args[index], "--kubeconfig=")
-			continue
-		}
-		if os.Args[index] == "--" {
-			break
-		}
-	}
+// subcommandsOwningUserFlags lists the kubectl subcommands defining flags
+// named like the global `--user`, `--server`, `--token` and `--cluster`
+// ones. For them these flags don't change the connection to the API server.
+var subcommandsOwningUserFlags = [][]string{ //nolint: gochecknoglobals // arrays cannot be go constants
+	{"create", "rolebinding"},
+	{"create", "clusterrolebinding"},
+	{"create", "token"},
+}
 
+// kubeconfigOptions returns the rules used to load the kubeconfig files and
+// the overrides specified by the user via the kubectl flags. The `args`
+// parameter holds the arguments given to kubectl, without the name of the
+// binary. Only the kubectl global flags that change the connection to the
+// API server, like `--context`, `--server` or `--token`, are taken into
+// account. The overrides are ignored for the `config` subcommands, which
+// use these flags to change the kubeconfig files.
+func kubeconfigOptions(args []string) (*clientcmd.ClientConfigLoadingRules, *clientcmd.ConfigOverrides) {
 	// Let the NewDefaultClientConfigLoadingRules do the heavy lifting like
 	// parsing the KUBECONFIG value
 	// TIL: it's possible to specify multiple kubeconfig files via KUBECONFIG
@@ -47,23 +39,69 @@ func createKubeClient(timeout int64) (*kubernetes.Clientset, error) {
 	// The NewDefaultClientConfigLoadingRules function has all the logic built
 	// inside of it that handles this special case.
 	clientConfLoadingrules := clientcmd.NewDefaultClientConfigLoadingRules()
-	if cliKubeconfig != "" {
-		clientConfLoadingrules.ExplicitPath = cliKubeconfig
-	}
 	clientConfOverrides := &clientcmd.ConfigOverrides{}
-	if cliKubecontext != "" {
-		clientConfOverrides.CurrentContext = cliKubecontext
+
+	flags := pflag.NewFlagSet("kubectl", pflag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	flags.Usage = func() {}
+	// the flags of the kubectl subcommands are unknown
+	flags.ParseErrorsAllowlist.UnknownFlags = true
+
+	flags.StringVar(&clientConfLoadingrules.ExplicitPath, clientcmd.RecommendedConfigPathFlag, "", "")
+	flagNames := clientcmd.RecommendedConfigOverrideFlags("")
+	// kubectl accepts `-s` as shorthand of `--server`
+	flagNames.ClusterOverrideFlags.APIServer.ShortName = "s"
+	clientcmd.BindOverrideFlags(clientConfOverrides, flags, flagNames)
+	// prevent pflag from handling `--help` and `-h` on its own
+	flags.BoolP("help", "h", false, "")
+
+	// If there are mutiple occurrences of options supplied to kubectl, the
+	// last one takes precedence, pflag preserves this behaviour. Everything
+	// after `--` is not parsed.
+	if err := flags.Parse(args); err != nil {
+		klog.V(common.VerbosityOne).Infof("cannot parse kubectl flags: %v", err)
 	}
-	restConfig, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
-		clientConfLoadingrules,
-		clientConfOverrides).ClientConfig()
+
+	subcommand := flags.Args()
+	if dash := flags.ArgsLenAtDash(); dash >= 0 {
+		subcommand = subcommand[:dash]
+	}
+	switch {
+	case len(subcommand) > 0 && subcommand[0] == "config":
+		clientConfOverrides = &clientcmd.ConfigOverrides{}
+	case ownsUserFlags(subcommand):
+		clientConfOverrides.Context.AuthInfo = ""
+		clientConfOverrides.Context.Cluster = ""
+		clientConfOverrides.ClusterInfo.Server = ""
+		clientConfOverrides.AuthInfo.Token = ""
+	}
+
+	return clientConfLoadingrules, clientConfOverrides
+}
+
+// ownsUserFlags returns true when the given positional arguments start with
+// a subcommand listed by subcommandsOwningUserFlags.
+func ownsUserFlags(positional []string) bool {
+	return slices.ContainsFunc(subcommandsOwningUserFlags, func(subcommand []string) bool {
+		return len(positional) >= len(subcommand) && slices.Equal(positional[:len(subcommand)], subcommand)
+	})
+}
+
+func createClientConfig(args []string) clientcmd.ClientConfig {
+	return clientcmd.NewNonInteractiveDeferredLoadingClientConfig(kubeconfigOptions(args))
+}
+
+func createRestConfig(timeout int64, args []string) (*rest.Config, error) {
+	restConfig, err := createClientConfig(args).ClientConfig()
 	if err != nil {
 		return nil, err
 	}
 
-	// Lower the timeout value
-	restConfig.Timeout = time.Duration(timeout) * time.Second
+	// Lower the timeout value, unless the user asked for a specific one
+	// via `--request-timeout`
+	if restConfig.Timeout == 0 {
+		restConfig.Timeout = time.Duration(timeout) * time.Second
+	}
 
-	// Create the clientset
-	return kubernetes.NewForConfig(restConfig)
+	return restConfig, nil
 }
@@ -0,0 +1,109 @@
+package kubehelper
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKubeconfigOptions(t *testing.T) {
+	loadingRules, overrides := kubeconfigOptions([]string{
+		"--context", "dev",
+		"get", "pods",
+		"-n", "kube-system",
+		"--kubeconfig=/tmp/kubeconfig",
+		"-s", "https://10.0.0.1:6443",
+		"--token=secret",
+		"--as", "admin",
+		"--insecure-skip-tls-verify",
+		"--request-timeout=30s",
+		"-o", "wide",
+		"--context=prod",
+	})
+
+	assert.Equal(t, "/tmp/kubeconfig", loadingRules.ExplicitPath)
+	// the last occurrence wins
+	assert.Equal(t, "prod", overrides.CurrentContext)
+	assert.Equal(t, "https://10.0.0.1:6443", overrides.ClusterInfo.Server)
+	assert.True(t, overrides.ClusterInfo.InsecureSkipTLSVerify)
+	assert.Equal(t, "secret", overrides.AuthInfo.Token)
+	assert.Equal(t, "admin", overrides.AuthInfo.Impersonate)
+	assert.Equal(t, "30s", overrides.Timeout)
+	assert.Equal(t, "kube-system", overrides.Context.Namespace)
+}
+
+func TestKubeconfigOptionsStopsAtDoubleDash(t *testing.T) {
+	_, overrides := kubeconfigOptions([]string{
+		"exec", "-it", "my-pod", "--cluster", "dev", "--", "sh", "-c", "kubectl --context=prod get pods",
+		"--user=root",
+	})
+
+	assert.Equal(t, "dev", overrides.Context.Cluster)
+	assert.Empty(t, overrides.CurrentContext)
+	assert.Empty(t, overrides.Context.AuthInfo)
+}
+
+func TestKubeconfigOptionsHelp(t *testing.T) {
+	_, overrides := kubeconfigOptions([]string{"get", "-h", "--context", "dev"})
+
+	assert.Equal(t, "dev", overrides.CurrentContext)
+}
+
+func TestKubeconfigOptionsSubcommandFlags(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{
+			name: "config set-cluster",
+			args: []string{"config", "set-cluster", "staging", "--server=https://new", "--insecure-skip-tls-verify=true"},
+		},
+		{
+			name: "config set-credentials",
+			args: []string{"config", "set-credentials", "bob", "--token=T"},
+		},
+		{
+			name: "config set-context",
+			args: []string{"--kubeconfig", "/tmp/kubeconfig", "config", "set-context", "dev", "--cluster=dev", "--user=bob"},
+		},
+		{
+			name: "create rolebinding",
+			args: []string{"create", "rolebinding", "x", "--clusterrole=view", "--user=alice"},
+		},
+		{
+			name: "create clusterrolebinding",
+			args: []string{"create", "clusterrolebinding", "x", "--clusterrole=view", "--user", "alice"},
+		},
+		{
+			name: "create token",
+			args: []string{"-n", "default", "create", "token", "robot", "--token=T", "--server=https://new"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, overrides := kubeconfigOptions(tc.args)
+
+			assert.Empty(t, overrides.ClusterInfo.Server)
+			assert.False(t, overrides.ClusterInfo.InsecureSkipTLSVerify)
+			assert.Empty(t, overrides.AuthInfo.Token)
+			assert.Empty(t, overrides.Context.AuthInfo)
+			assert.Empty(t, overrides.Context.Cluster)
+		})
+	}
+}
+
+func TestKubeconfigOptionsConfigKeepsKubeconfig(t *testing.T) {
+	loadingRules, overrides := kubeconfigOptions([]string{
+		"--kubeconfig=/tmp/kubeconfig", "config", "set-cluster", "staging", "--server=https://new",
+	})
+
+	assert.Equal(t, "/tmp/kubeconfig", loadingRules.ExplicitPath)
+	assert.Empty(t, overrides.ClusterInfo.Server)
+}
+
+func TestKubeconfigOptionsOtherSubcommands(t *testing.T) {
+	_, overrides := kubeconfigOptions([]string{"create", "deployment", "x", "--image=nginx", "--user=alice"})
+
+	assert.Equal(t, "alice", overrides.Context.AuthInfo)
+}
@@ -0,0 +1,117 @@
+package kubehelper
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/flavio/kuberlr/internal/osexec"
+)
+
+// ContextInfo describes the kubeconfig context kubectl is going to use.
+type ContextInfo struct {
+	// Name of the kubeconfig context
+	Name string
+	// Cluster is the name of the cluster referenced by the context
+	Cluster string
+	// Server is the URL of the API server of the cluster
+	Server string
+	// Kubeconfig lists the kubeconfig files that have been read
+	Kubeconfig []string
+}
+
+// CurrentContext returns information about the kubeconfig context that is
+// going to be used by kubectl when invoked with the given arguments. The
+// remote API server is not contacted.
+func CurrentContext(args []string) (ContextInfo, error) {
+	loadingRules, overrides := kubeconfigOptions(args)
+
+	rawConfig, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+		loadingRules, overrides).RawConfig()
+	if err != nil {
+		return ContextInfo{}, err
+	}
+
+	info := ContextInfo{
+		Name:       rawConfig.CurrentContext,
+		Kubeconfig: kubeconfigFiles(loadingRules),
+	}
+	if overrides.CurrentContext != "" {
+		info.Name = overrides.CurrentContext
+	}
+
+	if kubeContext, found := rawConfig.Contexts[info.Name]; found {
+		info.Cluster = kubeContext.Cluster
+	}
+	if overrides.Context.Cluster != "" {
+		info.Cluster = overrides.Context.Cluster
+	}
+
+	if cluster, found := rawConfig.Clusters[info.Cluster]; found {
+		info.Server = cluster.Server
+	}
+	if overrides.ClusterInfo.Server != "" {
+		info.Server = overrides.ClusterInfo.Server
+	}
+
+	return info, nil
+}
+
+// kubeconfigFiles returns the existing kubeconfig files referenced by the
+// loading rules.
+func kubeconfigFiles(loadingRules *clientcmd.ClientConfigLoadingRules) []string {
+	if loadingRules.ExplicitPath != "" {
+		return []string{loadingRules.ExplicitPath}
+	}
+
+	files := []string{}
+	for _, path := range loadingRules.GetLoadingPrecedence() {
+		if _, err := os.Stat(path); err == nil {
+			files = append(files, path)
+		}
+	}
+
+	return files
+}
+
+// KubectlExecPlugins returns the names of the kubeconfig users whose
+// credentials are obtained by running kubectl, like `kubectl oidc-login`.
+// When kubectl is kuberlr, these plugins invoke kuberlr while it is looking
+// for the version of the API server.
+func KubectlExecPlugins(args []string) ([]string, error) {
+	rawConfig, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+		kubeconfigOptions(args)).RawConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	users := []string{}
+	for name, authInfo := range rawConfig.AuthInfos {
+		if authInfo.Exec != nil && osexec.TrimExt(filepath.Base(authInfo.Exec.Command)) == "kubectl" {
+			users = append(users, name)
+		}
+	}
+	sort.Strings(users)
+
+	return users, nil
+}
+
+// ContextNames returns the names of all the contexts defined by the
+// kubeconfig files kubectl reads when invoked with the given arguments.
+func ContextNames(args []string) ([]string, error) {
+	rawConfig, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+		kubeconfigOptions(args)).RawConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	names := []string{}
+	for name := range rawConfig.Contexts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names, nil
+}
@@ -0,0 +1,85 @@
+package kubehelper
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testKubeconfig = `
+apiVersion: v1
+kind: Config
+current-context: dev
+clusters:
+- name: dev
+  cluster:
+    server: https://dev.example.com:6443
+- name: prod
+  cluster:
+    server: https://prod.example.com:6443
+contexts:
+- name: dev
+  context:
+    cluster: dev
+    user: dev-admin
+- name: prod
+  context:
+    cluster: prod
+    user: oidc
+users:
+- name: dev-admin
+  user:
+    token: secret
+- name: oidc
+  user:
+    exec:
+      apiVersion: client.authentication.k8s.io/v1beta1
+      command: kubectl
+      args: ["oidc-login", "get-token"]
+`
+
+func writeTestKubeconfig(t *testing.T) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "kubeconfig")
+	require.NoError(t, os.WriteFile(path, []byte(testKubeconfig), 0o600))
+	return path
+}
+
+func TestCurrentContext(t *testing.T) {
+	kubeconfig := writeTestKubeconfig(t)
+
+	info, err := CurrentContext([]string{"--kubeconfig", kubeconfig, "get", "pods"})
+	require.NoError(t, err)
+	assert.Equal(t, ContextInfo{
+		Name:       "dev",
+		Cluster:    "dev",
+		Server:     "https://dev.example.com:6443",
+		Kubeconfig: []string{kubeconfig},
+	}, info)
+
+	info, err = CurrentContext([]string{"--kubeconfig", kubeconfig, "--context=prod", "-s", "https://10.0.0.1"})
+	require.NoError(t, err)
+	assert.Equal(t, "prod", info.Name)
+	assert.Equal(t, "prod", info.Cluster)
+	assert.Equal(t, "https://10.0.0.1", info.Server)
+}
+
+func TestKubectlExecPlugins(t *testing.T) {
+	kubeconfig := writeTestKubeconfig(t)
+
+	users, err := KubectlExecPlugins([]string{"--kubeconfig", kubeconfig})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"oidc"}, users)
+}
+
+func TestContextNames(t *testing.T) {
+	kubeconfig := writeTestKubeconfig(t)
+
+	names, err := ContextNames([]string{"--kubeconfig", kubeconfig})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"dev", "prod"}, names)
+}
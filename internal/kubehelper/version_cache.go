@@ -0,0 +1,149 @@
+package kubehelper
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/blang/semver/v4"
+	"k8s.io/client-go/rest"
+	"k8s.io/klog"
+
+	"github.com/flavio/kuberlr/internal/common"
+)
+
+// ServerVersionCacheFileName is the name of the file where kuberlr caches
+// the versions of the API servers it talked with.
+const ServerVersionCacheFileName = "server-versions.json"
+
+// ServerVersionCacheEntry is the last known version of an API server.
+type ServerVersionCacheEntry struct {
+	// Server is the URL of the API server
+	Server string `json:"server"`
+	// CAFingerprint is the SHA256 of the certificate authority of the
+	// cluster, it's empty when the cluster doesn't have one
+	CAFingerprint string    `json:"caFingerprint"`
+	Version       string    `json:"version"`
+	CheckedAt     time.Time `json:"checkedAt"`
+}
+
+// Expired returns true when the entry is older than the given TTL.
+func (e ServerVersionCacheEntry) Expired(ttl time.Duration) bool {
+	return time.Since(e.CheckedAt) >= ttl
+}
+
+// CachedAt returns when the version has been checked.
+func (e ServerVersionCacheEntry) CachedAt() time.Time {
+	return e.CheckedAt
+}
+
+// ServerVersionCache keeps the versions of the API servers on disk, keyed
+// by server URL and certificate authority fingerprint. Fresh entries are
+// used instead of contacting the API server, expired ones are used only
+// when the API server cannot be reached. The cache can be used by
+// multiple goroutines at the same time.
+type ServerVersionCache struct {
+	cache *common.JSONCache[ServerVersionCacheEntry]
+}
+
+// DefaultServerVersionCachePath returns the path to the file used to cache
+// the versions of the API servers.
+func DefaultServerVersionCachePath() string {
+	return filepath.Join(common.KuberlrDir(), ServerVersionCacheFileName)
+}
+
+// LoadServerVersionCache reads the cache stored at the given path. A missing
+// file results in an empty cache. Entries older than ttl are considered
+// expired. A usable, empty, cache is returned together with the error when
+// the file cannot be read.
+func LoadServerVersionCache(path string, ttl time.Duration) (*ServerVersionCache, error) {
+	cache, err := common.LoadJSONCache[ServerVersionCacheEntry](path, ttl)
+	return &ServerVersionCache{cache: cache}, err
+}
+
+// TTL returns how long the entries of the cache are considered fresh.
+func (c *ServerVersionCache) TTL() time.Duration {
+	return c.cache.TTL()
+}
+
+// Disabled returns true when the cache must not be used, neither to avoid
+// contacting the API server nor to replace an unreachable one. A TTL of
+// zero disables the cache.
+func (c *ServerVersionCache) Disabled() bool {
+	return c == nil || c.TTL() == 0
+}
+
+// Entries returns all the entries of the cache, sorted by server URL.
+func (c *ServerVersionCache) Entries() []ServerVersionCacheEntry {
+	entries := c.cache.Entries()
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].Server != entries[j].Server {
+			return entries[i].Server < entries[j].Server
+		}
+		return entries[i].CAFingerprint < entries[j].CAFingerprint
+	})
+
+	return entries
+}
+
+// Lookup returns the last known version of the API server identified by the
+// given URL and certificate authority fingerprint. The first boolean tells
+// whether the server has been found, the second one whether the entry is
+// still fresh.
+func (c *ServerVersionCache) Lookup(server, caFingerprint string) (semver.Version, bool, bool) {
+	entry, found, fresh := c.cache.Get(cacheKey(server, caFingerprint))
+	if !found {
+		return semver.Version{}, false, false
+	}
+
+	version, err := semver.Parse(entry.Version)
+	if err != nil {
+		return semver.Version{}, false, false
+	}
+
+	return version, true, fresh
+}
+
+// Store records the version of the given API server and writes the cache
+// to disk.
+func (c *ServerVersionCache) Store(server, caFingerprint string, version semver.Version) error {
+	return c.cache.Put(cacheKey(server, caFingerprint), ServerVersionCacheEntry{
+		Server:        server,
+		CAFingerprint: caFingerprint,
+		Version:       version.String(),
+		CheckedAt:     time.Now(),
+	})
+}
+
+// Clear removes all the entries of the cache, including the file on disk.
+func (c *ServerVersionCache) Clear() error {
+	return c.cache.Clear()
+}
+
+func cacheKey(server, caFingerprint string) string {
+	return server + "#" + caFingerprint
+}
+
+// caFingerprint returns the SHA256 of the certificate authority used to
+// verify the API server, or an empty string when there's none.
+func caFingerprint(restConfig *rest.Config) string {
+	caData := restConfig.CAData
+	if len(caData) == 0 && restConfig.CAFile != "" {
+		var err error
+		caData, err = os.ReadFile(restConfig.CAFile)
+		if err != nil {
+			klog.V(common.VerbosityTwo).Infof("cannot read certificate authority %s: %v", restConfig.CAFile, err)
+			return ""
+		}
+	}
+	if len(caData) == 0 {
+		return ""
+	}
+
+	sum := sha256.Sum256(caData)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,135 @@
+package kubehelper
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/blang/semver/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServerVersionCache(t *testing.T) {
+	cachePath := filepath.Join(t.TempDir(), ServerVersionCacheFileName)
+
+	cache, err := LoadServerVersionCache(cachePath, time.Hour)
+	require.NoError(t, err)
+	_, found, _ := cache.Lookup("https://prod:6443", "abc")
+	assert.False(t, found)
+
+	require.NoError(t, cache.Store("https://prod:6443", "abc", semver.MustParse("1.28.4")))
+	require.NoError(t, cache.Store("https://dev:6443", "", semver.MustParse("1.31.0-rc.1")))
+
+	cache, err = LoadServerVersionCache(cachePath, time.Hour)
+	require.NoError(t, err)
+
+	version, found, fresh := cache.Lookup("https://prod:6443", "abc")
+	assert.True(t, found)
+	assert.True(t, fresh)
+	assert.Equal(t, semver.MustParse("1.28.4"), version)
+
+	// same server, different certificate authority: another cluster
+	_, found, _ = cache.Lookup("https://prod:6443", "def")
+	assert.False(t, found)
+
+	entries := cache.Entries()
+	require.Len(t, entries, 2)
+	assert.Equal(t, "https://dev:6443", entries[0].Server)
+	assert.Equal(t, "1.31.0-rc.1", entries[0].Version)
+
+	require.NoError(t, cache.Clear())
+	_, err = os.Stat(cachePath)
+	assert.True(t, os.IsNotExist(err))
+	assert.Empty(t, cache.Entries())
+}
+
+func TestServerVersionCacheExpiredEntry(t *testing.T) {
+	cachePath := filepath.Join(t.TempDir(), ServerVersionCacheFileName)
+
+	cache, err := LoadServerVersionCache(cachePath, 0)
+	require.NoError(t, err)
+	require.NoError(t, cache.Store("https://prod:6443", "", semver.MustParse("1.28.4")))
+
+	// expired entries are still returned, they are used when the API
+	// server cannot be reached
+	version, found, fresh := cache.Lookup("https://prod:6443", "")
+	assert.True(t, found)
+	assert.False(t, fresh)
+	assert.Equal(t, semver.MustParse("1.28.4"), version)
+}
+
+func TestServerVersionCacheCorrupted(t *testing.T) {
+	cachePath := filepath.Join(t.TempDir(), ServerVersionCacheFileName)
+	require.NoError(t, os.WriteFile(cachePath, []byte("{not json"), 0o600))
+
+	cache, err := LoadServerVersionCache(cachePath, time.Hour)
+	require.Error(t, err)
+	assert.Empty(t, cache.Entries())
+}
+
+func TestKubeAPICachedVersion(t *testing.T) {
+	kubeconfig := writeTestKubeconfig(t)
+	cache, err := LoadServerVersionCache(filepath.Join(t.TempDir(), ServerVersionCacheFileName), time.Nanosecond)
+	require.NoError(t, err)
+	require.NoError(t, cache.Store("https://prod.example.com:6443", "", semver.MustParse("1.28.4")))
+
+	api := KubeAPI{VersionCache: cache}
+
+	version, found, err := api.CachedVersion([]string{"--kubeconfig", kubeconfig, "--context", "prod"})
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, semver.MustParse("1.28.4"), version)
+
+	_, found, err = api.CachedVersion([]string{"--kubeconfig", kubeconfig, "--context", "dev"})
+	require.NoError(t, err)
+	assert.False(t, found)
+}
+
+func TestKubeAPIVersionCacheDisabled(t *testing.T) {
+	kubeconfig := writeTestKubeconfig(t)
+	cachePath := filepath.Join(t.TempDir(), ServerVersionCacheFileName)
+	cache, err := LoadServerVersionCache(cachePath, 0)
+	require.NoError(t, err)
+	require.True(t, cache.Disabled())
+	// left behind while the cache was enabled
+	require.NoError(t, cache.Store("https://127.0.0.1:1", "", semver.MustParse("1.28.4")))
+
+	api := KubeAPI{VersionCache: cache}
+	args := []string{"--kubeconfig", kubeconfig, "--context", "prod", "--server", "https://127.0.0.1:1"}
+
+	// the API server cannot be reached, the last known version is not used
+	_, err = api.Version(1, args)
+	require.Error(t, err)
+
+	_, found, err := api.CachedVersion(args)
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	cache, err = LoadServerVersionCache(cachePath, time.Hour)
+	require.NoError(t, err)
+	assert.Len(t, cache.Entries(), 1)
+}
+
+func TestServerVersionCacheConcurrentUsage(t *testing.T) {
+	cache, err := LoadServerVersionCache(filepath.Join(t.TempDir(), ServerVersionCacheFileName), time.Hour)
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			server := fmt.Sprintf("https://cluster-%d:6443", i)
+			assert.NoError(t, cache.Store(server, "", semver.MustParse("1.28.4")))
+			_, found, _ := cache.Lookup(server, "")
+			assert.True(t, found)
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Len(t, cache.Entries(), 10)
+}
@@ -18,10 +18,10 @@ var (
 
 // KVersion holds the build-time information of kuberlr.
 type KVersion struct {
-	Version   string
-	BuildDate string
-	Tag       string
-	GoVersion string
+	Version   string `json:"version"`
+	BuildDate string `json:"buildDate"`
+	Tag       string `json:"tag"`
+	GoVersion string `json:"goVersion"`
 }
 
 // CurrentVersion returns the information about the current version of kuberlr.